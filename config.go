@@ -0,0 +1,185 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Config is the content of a configuration file, listing the man pages to
+// generate in a single run. Relative paths are resolved from the directory
+// of the configuration file.
+type Config struct {
+	// Options are the options shared by all the pages, by name and without
+	// leading dash, e.g. {"section": "8"}. Boolean options can be set
+	// with true or false.
+	Options map[string]any `json:"options"`
+	// Pages are the man pages to generate.
+	Pages []*ConfigPage `json:"pages"`
+}
+
+// ConfigPage is a man page to generate from a configuration file.
+type ConfigPage struct {
+	// Executable is the program to document, as given on the command line.
+	Executable string `json:"executable"`
+	// Include is the path of an include file, which must exist.
+	Include string `json:"include"`
+	// Output is the path of the generated man page.
+	Output string `json:"output"`
+	// Options override the shared options for this page.
+	Options map[string]any `json:"options"`
+}
+
+// readConfig reads and validates the configuration file at path.
+func readConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	d := json.NewDecoder(bytes.NewReader(content))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&config); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(config.Pages) == 0 {
+		return nil, fmt.Errorf("%s: no pages", path)
+	}
+	for i, p := range config.Pages {
+		if p.Output == "" {
+			return nil, fmt.Errorf("%s: page %d: missing output", path, i+1)
+		}
+	}
+	return &config, nil
+}
+
+// optionArgs returns the command line arguments that set the given options,
+// sorted by name.
+func optionArgs(options map[string]any) (args []string) {
+	names := make([]string, 0, len(options))
+	for n := range options {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		args = append(args, fmt.Sprintf("-%s=%v", n, options[n]))
+	}
+	return
+}
+
+// args returns the command line arguments to generate the page p, starting
+// with the shared options of c and ending with extra.
+func (c *Config) args(p *ConfigPage, extra []string) []string {
+	args := append(optionArgs(c.Options), optionArgs(p.Options)...)
+	if p.Include != "" {
+		args = append(args, "-include", p.Include)
+	}
+	args = append(args, "-output", p.Output)
+	args = append(args, extra...)
+	if p.Executable != "" {
+		args = append(args, "--", p.Executable)
+	}
+	return args
+}
+
+// pageFlags are the flags that only make sense for a single page, which are
+// set in the configuration file rather than on the command line.
+var pageFlags = []string{"help-file", "include", "name", "opt-include", "output", "program"}
+
+// visitedPageFlags returns the names of the pageFlags of cli that have been
+// set.
+func visitedPageFlags(cli *flag.FlagSet) (names []string) {
+	cli.Visit(func(f *flag.Flag) {
+		for _, n := range pageFlags {
+			if f.Name == n {
+				names = append(names, "-"+n)
+			}
+		}
+	})
+	return
+}
+
+// visitedArgs returns the arguments of the flags of cli that have been set,
+// except the ones in ignore.
+func visitedArgs(cli *flag.FlagSet, ignore ...string) (args []string) {
+	cli.Visit(func(f *flag.Flag) {
+		for _, n := range ignore {
+			if f.Name == n {
+				return
+			}
+		}
+		if list, ok := f.Value.(*listFlag); ok {
+			for _, v := range *list {
+				args = append(args, "-"+f.Name+"="+v)
+			}
+			return
+		}
+		args = append(args, "-"+f.Name+"="+f.Value.String())
+	})
+	return
+}
+
+// runConfig generates in parallel the pages of the configuration file at path,
+// by running the executable self for each of them with the extra arguments.
+// The output of each run is printed in order, and an error is returned if any
+// of them failed.
+func runConfig(self, path string, extra []string) error {
+	config, err := readConfig(path)
+	if err != nil {
+		return err
+	}
+	outputs := make([][]byte, len(config.Pages))
+	errs := make([]error, len(config.Pages))
+	sem := make(chan struct{}, runtime.NumCPU())
+	var wg sync.WaitGroup
+	for i, p := range config.Pages {
+		wg.Add(1)
+		go func(i int, p *ConfigPage) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			cmd := exec.Command(self, config.args(p, extra)...)
+			cmd.Dir = filepath.Dir(path)
+			// The options of the environment are already part of extra.
+			cmd.Env = append(os.Environ(), "GOH2M_OPTIONS=")
+			outputs[i], errs[i] = cmd.CombinedOutput()
+		}(i, p)
+	}
+	wg.Wait()
+	failed := 0
+	for i, p := range config.Pages {
+		os.Stdout.Write(outputs[i])
+		if errs[i] != nil {
+			l.Printf("page %s: %v", p.Output, errs[i])
+			failed++
+		}
+	}
+	if failed != 0 {
+		return fmt.Errorf("%d of %d pages failed", failed, len(config.Pages))
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadConfig(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"valid", `{"pages": [{"executable": "./cmd/a", "output": "a.1"}]}`, ""},
+		{"no pages", `{"options": {"section": "8"}}`, "no pages"},
+		{"missing output", `{"pages": [{"executable": "./cmd/a"}]}`, "page 1: missing output"},
+		{"unknown field", `{"pages": [{"exe": "./cmd/a", "output": "a.1"}]}`, "unknown field"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "gohelp2man.json")
+			if err := os.WriteFile(path, []byte(c.content), 0o666); err != nil {
+				t.Fatal(err)
+			}
+			_, err := readConfig(path)
+			if c.err == "" && err != nil {
+				t.Fatal(err)
+			}
+			if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+				t.Fatalf("expected error to contain %q, got %v", c.err, err)
+			}
+		})
+	}
+}
+
+func TestConfigArgs(t *testing.T) {
+	config := &Config{Options: map[string]any{"section": 8.0, "manual": "Tools"}}
+	page := &ConfigPage{
+		Executable: "./cmd/a",
+		Include:    "a.h2m",
+		Output:     "a.8",
+		Options:    map[string]any{"no-group": true},
+	}
+	expected := []string{
+		"-manual=Tools", "-section=8", "-no-group=true",
+		"-include", "a.h2m", "-output", "a.8", "-check=true", "--", "./cmd/a",
+	}
+	actual := config.args(page, []string{"-check=true"})
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestRunConfig(t *testing.T) {
+	self, clean, err := buildPackage(".", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean()
+	dir := t.TempDir()
+	for _, name := range []string{"config.json", "test_full_basic.txt", "test_full_grouped.txt", "test_full_grouped.h2m"} {
+		content, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := filepath.Join(dir, "config.json")
+	if err := runConfig(self, config, nil); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"basic.8":   ".TH BASIC 8 ",
+		"grouped.8": ".TH GROUPED 8 ",
+	}
+	for file, part := range expected {
+		actual, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(actual), part) || !strings.Contains(string(actual), "v1.2.3") {
+			t.Errorf("expected %s to contain %q and the version, got:\n%s", file, part, actual)
+		}
+	}
+
+	if err := runConfig(self, config, []string{"-check", "-section=1"}); err == nil {
+		t.Fatal("expected stale pages to be reported")
+	} else if !strings.Contains(err.Error(), "2 of 2 pages failed") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestVisitedPageFlags(t *testing.T) {
+	cli := flag.NewFlagSet("test", flag.ContinueOnError)
+	for _, n := range []string{"output", "include", "section"} {
+		cli.String(n, "", "")
+	}
+	if err := cli.Parse([]string{"-section=8", "-output=a.1", "-include=a.h2m"}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"-include", "-output"}
+	if actual := visitedPageFlags(cli); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %q, got %q", expected, actual)
+	}
+}
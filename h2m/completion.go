@@ -0,0 +1,342 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Shells for which completion scripts can be written.
+const (
+	ShellBash = "bash"
+	ShellZsh  = "zsh"
+	ShellFish = "fish"
+)
+
+// Kinds of completion of the argument of a flag.
+const (
+	completeNone = iota
+	completeFile
+	completeDir
+)
+
+var (
+	regexCompletionDir  = regexp.MustCompile(`(?i)^(?:DIR|DIRECTORY|FOLDER)S?$|DIR$`)
+	regexCompletionFile = regexp.MustCompile(`(?i)FILE|PATH`)
+	regexFuncName       = regexp.MustCompile(`\W`)
+)
+
+// completion returns the kind of completion of the argument of f, from its
+// name, e.g. FILE or DIR.
+func (f *Flag) completion() int {
+	switch {
+	case regexCompletionDir.MatchString(f.argName()):
+		return completeDir
+	case regexCompletionFile.MatchString(f.argName()):
+		return completeFile
+	default:
+		return completeNone
+	}
+}
+
+// names returns the names of f and of its aliases, with their leading dash.
+func (f *Flag) names() []string {
+	names := []string{"-" + f.Name}
+	for _, a := range f.Aliases {
+		names = append(names, "-"+a)
+	}
+	return names
+}
+
+// summary returns the first line of the usage of f, without its default.
+func (f *Flag) summary() string {
+	s, _, _ := strings.Cut(f.usageWithoutDefault(), "\n")
+	return s
+}
+
+// completedCommands returns the commands of h whose help output has been
+// retrieved.
+func (h *Help) completedCommands() (commands []*Command) {
+	for _, c := range h.Commands {
+		if c.Help != nil {
+			commands = append(commands, c)
+		}
+	}
+	return
+}
+
+// WriteCompletion writes in w the completion script of the program name for
+// the given shell, made of the flags and the commands of help. The flags of
+// the commands are completed too, if their help has been retrieved. The
+// argument of a flag is completed with file names if it looks like a FILE or
+// a PATH, or with directory names if it looks like a DIR.
+func WriteCompletion(w io.Writer, shell, name string, help *Help) (err error) {
+	defer func() {
+		if !debugMode {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("%v", p)
+			}
+		}
+	}()
+
+	switch shell {
+	case ShellBash:
+		writeBashCompletion(w, name, help)
+	case ShellZsh:
+		writeZshCompletion(w, name, help)
+	case ShellFish:
+		writeFishCompletion(w, name, help)
+	default:
+		return fmt.Errorf("unknown shell %q", shell)
+	}
+	return
+}
+
+// writeBashCompletion writes in w the bash completion script of name.
+func writeBashCompletion(w io.Writer, name string, help *Help) {
+	fn := "_" + regexFuncName.ReplaceAllString(name, "_")
+	commands := help.completedCommands()
+	mfprintf(w, "# bash completion for %s, generated by gohelp2man %s; DO NOT EDIT.\n\n", name, Version())
+	mfprintf(w, "%s() {\n", fn)
+	mfprintln(w, "\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"")
+
+	// Complete the arguments of the flags.
+	args := make(map[int][]string)
+	seen := make(map[string]bool)
+	addArgs := func(flags []*Flag) {
+		for _, f := range flags {
+			for _, n := range f.names() {
+				if !f.IsBool && !seen[n] {
+					seen[n] = true
+					args[f.completion()] = append(args[f.completion()], n)
+				}
+			}
+		}
+	}
+	addArgs(help.Flags)
+	for _, c := range commands {
+		addArgs(c.Help.Flags)
+	}
+	if len(seen) != 0 {
+		mfprintln(w, "\tcase \"$prev\" in")
+		for _, kind := range []int{completeFile, completeDir, completeNone} {
+			if len(args[kind]) == 0 {
+				continue
+			}
+			mfprintf(w, "\t%s)\n", strings.Join(args[kind], "|"))
+			switch kind {
+			case completeFile:
+				mfprintln(w, "\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))")
+			case completeDir:
+				mfprintln(w, "\t\tCOMPREPLY=($(compgen -d -- \"$cur\"))")
+			}
+			mfprintln(w, "\t\treturn\n\t\t;;")
+		}
+		mfprintln(w, "\tesac")
+	}
+
+	// Complete the flags and the commands.
+	flagNames := func(flags []*Flag) string {
+		var names []string
+		for _, f := range flags {
+			names = append(names, f.names()...)
+		}
+		return strings.Join(names, " ")
+	}
+	var cmdNames []string
+	for _, c := range help.Commands {
+		cmdNames = append(cmdNames, c.Name)
+		cmdNames = append(cmdNames, c.Aliases...)
+	}
+	mfprintf(w, "\tlocal opts=%q cmd\n", flagNames(help.Flags))
+	if len(commands) != 0 {
+		mfprintln(w, "\tlocal word")
+		mfprintln(w, "\tfor word in \"${COMP_WORDS[@]:1:COMP_CWORD-1}\"; do")
+		mfprintln(w, "\t\tcase \"$word\" in")
+		for _, c := range commands {
+			mfprintf(w, "\t\t%s)\n", strings.Join(append([]string{c.Name}, c.Aliases...), "|"))
+			mfprintf(w, "\t\t\tcmd=%s opts=%q\n", c.Name, flagNames(c.Help.Flags))
+			mfprintln(w, "\t\t\tbreak\n\t\t\t;;")
+		}
+		mfprintln(w, "\t\tesac")
+		mfprintln(w, "\tdone")
+	}
+	mfprintln(w, "\tif [[ $cur == -* ]]; then")
+	mfprintln(w, "\t\tCOMPREPLY=($(compgen -W \"$opts\" -- \"$cur\"))")
+	if len(cmdNames) != 0 {
+		mfprintln(w, "\telif [[ -z $cmd ]]; then")
+		mfprintf(w, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(cmdNames, " "))
+	}
+	mfprintln(w, "\tfi")
+	mfprintln(w, "}")
+	mfprintf(w, "\ncomplete -o default -F %s %s\n", fn, name)
+}
+
+// zshQuote quotes s in single quotes for zsh.
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// zshSpecs returns the _arguments specifications of the given flags.
+func zshSpecs(flags []*Flag) (specs []string) {
+	for _, f := range flags {
+		desc := strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(f.summary())
+		var spec string
+		names := f.names()
+		if len(names) > 1 {
+			spec = zshQuote("("+strings.Join(names, " ")+")") + "{" + strings.Join(names, ",") + "}"
+		} else {
+			spec = names[0]
+		}
+		arg := "[" + desc + "]"
+		if !f.IsBool {
+			arg += ":" + strings.ReplaceAll(f.argName(), ":", "") + ":"
+			switch f.completion() {
+			case completeFile:
+				arg += "_files"
+			case completeDir:
+				arg += "_files -/"
+			}
+		}
+		if len(names) > 1 {
+			spec += zshQuote(arg)
+		} else {
+			spec = zshQuote(spec + arg)
+		}
+		specs = append(specs, spec)
+	}
+	return
+}
+
+// writeZshArguments writes in w a call to _arguments, indented by indent,
+// with the given options and specifications.
+func writeZshArguments(w io.Writer, indent, options string, specs []string) {
+	mfprintf(w, "%s_arguments%s", indent, options)
+	for _, s := range specs {
+		mfprintf(w, " \\\n%s\t%s", indent, s)
+	}
+	mfprintln(w)
+}
+
+// writeZshCompletion writes in w the zsh completion script of name.
+func writeZshCompletion(w io.Writer, name string, help *Help) {
+	fn := "_" + regexFuncName.ReplaceAllString(name, "_")
+	mfprintf(w, "#compdef %s\n", name)
+	mfprintf(w, "# zsh completion for %s, generated by gohelp2man %s; DO NOT EDIT.\n\n", name, Version())
+	mfprintf(w, "%s() {\n", fn)
+	specs := zshSpecs(help.Flags)
+	if len(help.Commands) == 0 {
+		writeZshArguments(w, "\t", "", append(specs, zshQuote("*:file:_files")))
+	} else {
+		mfprintln(w, "\tlocal curcontext=\"$curcontext\" state line")
+		writeZshArguments(w, "\t", " -C", append(specs, zshQuote("1:command:->command"), zshQuote("*::arg:->args")))
+		mfprintln(w, "\tcase $state in")
+		mfprintln(w, "\tcommand)")
+		mfprintln(w, "\t\tlocal -a commands=(")
+		for _, c := range help.Commands {
+			for _, n := range append([]string{c.Name}, c.Aliases...) {
+				mfprintf(w, "\t\t\t%s\n", zshQuote(strings.ReplaceAll(n, ":", `\:`)+":"+c.Usage))
+			}
+		}
+		mfprintln(w, "\t\t)")
+		mfprintln(w, "\t\t_describe command commands")
+		mfprintln(w, "\t\t;;")
+		mfprintln(w, "\targs)")
+		mfprintln(w, "\t\tcase $line[1] in")
+		for _, c := range help.completedCommands() {
+			mfprintf(w, "\t\t%s)\n", strings.Join(append([]string{c.Name}, c.Aliases...), "|"))
+			writeZshArguments(w, "\t\t\t", "", append(zshSpecs(c.Help.Flags), zshQuote("*:file:_files")))
+			mfprintln(w, "\t\t\t;;")
+		}
+		mfprintln(w, "\t\t*)")
+		mfprintln(w, "\t\t\t_files")
+		mfprintln(w, "\t\t\t;;")
+		mfprintln(w, "\t\tesac")
+		mfprintln(w, "\t\t;;")
+		mfprintln(w, "\tesac")
+	}
+	mfprintln(w, "}")
+	mfprintf(w, "\nif [ \"$funcstack[1]\" = %s ]; then\n\t%s \"$@\"\nelse\n\tcompdef %s %s\nfi\n", zshQuote(fn), fn, fn, name)
+}
+
+// fishQuote quotes s in single quotes for fish.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// writeFishFlags writes in w the completions of the given flags, with the
+// given condition.
+func writeFishFlags(w io.Writer, name, condition string, flags []*Flag) {
+	for _, f := range flags {
+		mfprintf(w, "complete -c %s", name)
+		if condition != "" {
+			mfprintf(w, " -n %s", fishQuote(condition))
+		}
+		for _, n := range f.names() {
+			switch {
+			case strings.HasPrefix(n, "--"):
+				mfprintf(w, " -l %s", n[2:])
+			case len(n) == 2:
+				mfprintf(w, " -s %s", n[1:])
+			default:
+				mfprintf(w, " -o %s", n[1:])
+			}
+		}
+		if !f.IsBool {
+			switch f.completion() {
+			case completeFile:
+				mfprint(w, " -r -F")
+			case completeDir:
+				mfprint(w, " -x -a '(__fish_complete_directories)'")
+			default:
+				mfprint(w, " -x")
+			}
+		}
+		if s := f.summary(); s != "" {
+			mfprintf(w, " -d %s", fishQuote(s))
+		}
+		mfprintln(w)
+	}
+}
+
+// writeFishCompletion writes in w the fish completion script of name.
+func writeFishCompletion(w io.Writer, name string, help *Help) {
+	mfprintf(w, "# fish completion for %s, generated by gohelp2man %s; DO NOT EDIT.\n\n", name, Version())
+	writeFishFlags(w, name, "", help.Flags)
+	if len(help.Commands) == 0 {
+		return
+	}
+	mfprintln(w)
+	for _, c := range help.Commands {
+		for _, n := range append([]string{c.Name}, c.Aliases...) {
+			mfprintf(w, "complete -c %s -f -n '__fish_use_subcommand' -a %s", name, fishQuote(n))
+			if c.Usage != "" {
+				mfprintf(w, " -d %s", fishQuote(c.Usage))
+			}
+			mfprintln(w)
+		}
+	}
+	for _, c := range help.completedCommands() {
+		mfprintln(w)
+		condition := "__fish_seen_subcommand_from " + strings.Join(append([]string{c.Name}, c.Aliases...), " ")
+		writeFishFlags(w, name, condition, c.Help.Flags)
+	}
+}
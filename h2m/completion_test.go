@@ -0,0 +1,112 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFlagCompletion(t *testing.T) {
+	cases := []struct {
+		arg      string
+		expected int
+	}{
+		{"FILE", completeFile},
+		{"file", completeFile},
+		{"CONFIG_PATH", completeFile},
+		{"DIR", completeDir},
+		{"directory", completeDir},
+		{"WORKDIR", completeDir},
+		{"=DIR", completeDir},
+		{"[=FILE]", completeFile},
+		{"string", completeNone},
+		{"ADDR", completeNone},
+		{"", completeNone},
+	}
+	for _, c := range cases {
+		t.Run(c.arg, func(t *testing.T) {
+			f := &Flag{Arg: c.arg}
+			if actual := f.completion(); actual != c.expected {
+				t.Fatalf("expected %d, got %d", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestWriteCompletion(t *testing.T) {
+	help := &Help{
+		Flags: []*Flag{
+			{Name: "o", Aliases: []string{"-output"}, Arg: "FILE", Usage: "Write to FILE.\nSecond line."},
+			{Name: "C", Arg: "DIR", Usage: "Change to DIR. (default \".\")", Default: "."},
+			{Name: "verbose", Usage: "Be [very] 'verbose'.", IsBool: true},
+		},
+		Commands: []*Command{
+			{Name: "build", Aliases: []string{"b"}, Usage: "Build it.", Help: &Help{
+				Flags: []*Flag{{Name: "n", Arg: "int", Usage: "Number of jobs."}},
+			}},
+			{Name: "version", Usage: "Print the version."},
+		},
+	}
+	cases := []struct {
+		shell    string
+		expected []string
+	}{
+		{ShellBash, []string{
+			"\t-o|--output)\n\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n",
+			"\t-C)\n\t\tCOMPREPLY=($(compgen -d -- \"$cur\"))\n",
+			"\t-n)\n\t\treturn\n",
+			"\tlocal opts=\"-o --output -C -verbose\" cmd\n",
+			"\t\tbuild|b)\n\t\t\tcmd=build opts=\"-n\"\n",
+			"COMPREPLY=($(compgen -W \"build b version\" -- \"$cur\"))",
+			"complete -o default -F _test_sh test.sh\n",
+		}},
+		{ShellZsh, []string{
+			"#compdef test.sh\n",
+			`'(-o --output)'{-o,--output}'[Write to FILE.]:FILE:_files'`,
+			`'-C[Change to DIR.]:DIR:_files -/'`,
+			`'-verbose[Be \[very\] '\''verbose'\''.]'`,
+			"\t\t\t'b:Build it.'\n",
+			"\t\tbuild|b)\n\t\t\t_arguments \\\n\t\t\t\t'-n[Number of jobs.]:int:'",
+			"\tcompdef _test_sh test.sh\n",
+		}},
+		{ShellFish, []string{
+			"complete -c test.sh -s o -l output -r -F -d 'Write to FILE.'\n",
+			"complete -c test.sh -s C -x -a '(__fish_complete_directories)' -d 'Change to DIR.'\n",
+			`complete -c test.sh -o verbose -d 'Be [very] \'verbose\'.'` + "\n",
+			"complete -c test.sh -f -n '__fish_use_subcommand' -a 'b' -d 'Build it.'\n",
+			"complete -c test.sh -n '__fish_seen_subcommand_from build b' -s n -x -d 'Number of jobs.'\n",
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.shell, func(t *testing.T) {
+			var b strings.Builder
+			if err := WriteCompletion(&b, c.shell, "test.sh", help); err != nil {
+				t.Fatal(err)
+			}
+			for _, part := range c.expected {
+				if !strings.Contains(b.String(), part) {
+					t.Errorf("expected to contain:\n%s\ngot:\n%s", part, b.String())
+				}
+			}
+		})
+	}
+	if err := WriteCompletion(&strings.Builder{}, "csh", "test.sh", help); err == nil {
+		t.Error("expected an error for an unknown shell")
+	}
+}
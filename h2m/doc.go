@@ -0,0 +1,197 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	regexDocLinkDef = regexp.MustCompile(`^\[([^\]]+)\]:\s+(\S+)$`)
+	regexDocLink    = regexp.MustCompile(`\[([^\]\n]+)\]`)
+	regexDocIdent   = regexp.MustCompile(`^\*?(?:[\w/.-]+\.)?\w+(?:\.\w+)?$`)
+	regexDocHeading = regexp.MustCompile(`^\p{Lu}[\p{L}\p{N} '()-]*[\p{L}\p{N})]$`)
+	regexDocList    = regexp.MustCompile(`^(?:([-*+•])|(\d+)[.)])\s+(.*)$`)
+)
+
+// PackageDoc returns the doc comment of the Go package found at path, which
+// can be an import path or a directory, converted to roff.
+func PackageDoc(path string) (string, error) {
+	pkg, err := importPackage(path, 0)
+	if err != nil {
+		return "", err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range append(pkg.GoFiles, pkg.CgoFiles...) {
+		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, parser.ParseComments|parser.PackageClauseOnly)
+		if err != nil {
+			return "", err
+		}
+		files = append(files, f)
+	}
+	p, err := doc.NewFromFiles(fset, files, pkg.ImportPath)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(p.Doc) == "" {
+		return "", fmt.Errorf("%s: no package doc comment", pkg.Dir)
+	}
+	return docToRoff(p.Doc), nil
+}
+
+// eDoc escapes and formats a text from a doc comment, which unlike the ones
+// of help outputs does not have implicit headers.
+func eDoc(s string) string {
+	return inlineFormatter.Replace(blockEscaper.Replace(s))
+}
+
+// isIndented reports whether line starts with a space or a tab.
+func isIndented(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
+
+// dedent removes the longest common indentation of lines.
+func dedent(lines []string) []string {
+	var prefix string
+	found := false
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			prefix, found = indent, true
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	dedented := make([]string, len(lines))
+	for i, line := range lines {
+		dedented[i] = strings.TrimPrefix(line, prefix)
+	}
+	return dedented
+}
+
+// docToRoff converts the text of a Go doc comment to roff. It supports
+// headings, paragraphs, code blocks, lists and links.
+func docToRoff(text string) string {
+	links := make(map[string]string)
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if m := regexDocLinkDef.FindStringSubmatch(line); m != nil {
+			links[m[1]] = m[2]
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t"))
+	}
+	linkify := func(s string) string {
+		return regexDocLink.ReplaceAllStringFunc(s, func(l string) string {
+			name := l[1 : len(l)-1]
+			if url, found := links[name]; found {
+				return name + " <" + url + ">"
+			}
+			if regexDocIdent.MatchString(name) {
+				return strings.TrimPrefix(name, "*")
+			}
+			return l
+		})
+	}
+
+	b := &strings.Builder{}
+	// A new paragraph is started unless at the beginning or after a heading.
+	var prev string
+	paragraph := func() {
+		if prev != "" && prev != "heading" {
+			mfprintln(b, ".PP")
+		}
+	}
+	for i := 0; i < len(lines); {
+		if lines[i] == "" {
+			i++
+			continue
+		}
+		if isIndented(lines[i]) {
+			start := i
+			for i < len(lines) && (isIndented(lines[i]) || lines[i] == "" && i+1 < len(lines) && isIndented(lines[i+1])) {
+				i++
+			}
+			block := dedent(lines[start:i])
+			if regexDocList.MatchString(block[0]) {
+				var item []string
+				writeItem := func() {
+					if item != nil {
+						mfprintln(b, eDoc(linkify(strings.Join(item, "\n"))))
+					}
+				}
+				for _, line := range block {
+					if m := regexDocList.FindStringSubmatch(line); m != nil {
+						writeItem()
+						if m[1] != "" {
+							mfprintln(b, `.IP \(bu 2`)
+						} else {
+							mfprintf(b, ".IP %s. 4\n", m[2])
+						}
+						item = []string{m[3]}
+					} else if line != "" {
+						item = append(item, strings.TrimSpace(line))
+					}
+				}
+				writeItem()
+				prev = "list"
+				continue
+			}
+			paragraph()
+			mfprintln(b, ".RS\n.nf")
+			for _, line := range block {
+				mfprintln(b, blockEscaper.Replace(line))
+			}
+			mfprintln(b, ".fi\n.RE")
+			prev = "code"
+			continue
+		}
+		start := i
+		for i < len(lines) && lines[i] != "" && !isIndented(lines[i]) {
+			i++
+		}
+		para := lines[start:i]
+		if len(para) == 1 && strings.HasPrefix(para[0], "# ") {
+			mfprintf(b, ".SS %s\n", eDoc(strings.TrimPrefix(para[0], "# ")))
+			prev = "heading"
+			continue
+		}
+		if len(para) == 1 && prev != "" && prev != "heading" && i+1 < len(lines) && lines[i+1] != "" && !isIndented(lines[i+1]) &&
+			regexDocHeading.MatchString(para[0]) {
+			mfprintf(b, ".SS %s\n", eDoc(para[0]))
+			prev = "heading"
+			continue
+		}
+		paragraph()
+		mfprintln(b, eDoc(linkify(strings.Join(para, "\n"))))
+		prev = "text"
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
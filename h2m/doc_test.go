@@ -0,0 +1,79 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"testing"
+)
+
+func TestPackageDoc(t *testing.T) {
+	expected := `Command doc greets people, see Greetings <https://example.com/greetings> and the flag package.
+.PP
+It reads the names from the standard input:
+.PP
+.RS
+.nf
+echo world | doc \-n 2
+.fi
+.RE
+.SS Options
+The options are:
+.IP \(bu 2
+\fB\-n\fP: the number of greetings.
+.IP \(bu 2
+\fB\-name\fP: the name to greet,
+if not read from the input.
+.SS Exit status
+It exits with:
+.IP 1. 4
+0 on success;
+.IP 2. 4
+1 on error.`
+	actual, err := PackageDoc("./testdata/doc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+
+	if _, err := PackageDoc("./testdata/static"); err == nil {
+		t.Fatal("expected an error without package doc comment")
+	}
+}
+
+func TestDocToRoff(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"old heading", "Intro.\n\nExit status\n\nText.\n", "Intro.\n.SS Exit status\nText."},
+		{"not a heading", "Intro.\n\nExit status.\n\nText.\n", "Intro.\n.PP\nExit status.\n.PP\nText."},
+		{"first line", "Exit status\n\nText.\n", "Exit status\n.PP\nText."},
+		{"unknown link", "See [the doc].\n", "See [the doc]."},
+		{"code with blank line", "Code:\n\n\ta\n\n\t.b\n", "Code:\n.PP\n.RS\n.nf\na\n\n\\&.b\n.fi\n.RE"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := docToRoff(c.input); actual != c.expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", c.expected, actual)
+			}
+		})
+	}
+}
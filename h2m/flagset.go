@@ -0,0 +1,90 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// isZeroValue reports whether the default value of f is the zero value of its
+// type, in which case it is not printed by [flag.PrintDefaults].
+func isZeroValue(f *flag.Flag) (zero bool) {
+	typ := reflect.TypeOf(f.Value)
+	var z reflect.Value
+	if typ.Kind() == reflect.Pointer {
+		z = reflect.New(typ.Elem())
+	} else {
+		z = reflect.Zero(typ)
+	}
+	// Like flag.PrintDefaults, consider a Value whose String method panics
+	// on its zero value as non-zero.
+	defer func() {
+		if recover() != nil {
+			zero = false
+		}
+	}()
+	return f.DefValue == z.Interface().(flag.Value).String()
+}
+
+// newFlag returns the Flag named name, with the given argument name and usage,
+// as it would be parsed from the output of [flag.PrintDefaults]. The default
+// value def is appended to the usage unless it is empty, quoted if quote is
+// set.
+func newFlag(name, arg, usage, def string, quote bool) *Flag {
+	lines := strings.Split(usage, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	usage = strings.Join(lines, "\n")
+	if quote {
+		usage += fmt.Sprintf(" (default %q)", def)
+	} else if def != "" {
+		usage += fmt.Sprintf(" (default %v)", def)
+	}
+	f := &Flag{Name: name, Arg: arg, Usage: strings.TrimSpace(usage)}
+	f.parseDetails()
+	return f
+}
+
+// FromFlagSet returns the Help of the flags defined in fs, as it would be
+// parsed from the output of [flag.PrintDefaults].
+func FromFlagSet(fs *flag.FlagSet) *Help {
+	h := &Help{Sections: make(map[string]*Section)}
+	fs.VisitAll(func(f *flag.Flag) {
+		arg, usage := flag.UnquoteUsage(f)
+		var def string
+		var quote bool
+		if !isZeroValue(f) {
+			def = f.DefValue
+			quote = reflect.TypeOf(f.Value).String() == "*flag.stringValue"
+		}
+		h.Flags = append(h.Flags, newFlag(f.Name, arg, usage, def, quote))
+	})
+	return h
+}
+
+// WriteFlagSet writes in w the man page p of the flags defined in fs and of
+// the given include, which may be nil. It is the same man page as the one
+// generated from the output of [flag.PrintDefaults].
+func WriteFlagSet(w io.Writer, fs *flag.FlagSet, p *Page, include *Include) error {
+	return Write(w, p, include, FromFlagSet(fs))
+}
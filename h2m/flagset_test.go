@@ -0,0 +1,48 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFromFlagSet(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Bool("v", false, "Enable verbose output.")
+	fs.Bool("color", true, "Colorize the output.")
+	fs.String("o", "", "Write the output to `FILE`.")
+	fs.String("name", "world", "The name to greet.")
+	fs.Int("n", 1, "Number of greetings.\nCan be repeated.")
+	fs.Duration("timeout", 5*time.Second, "Maximum duration.")
+
+	var out strings.Builder
+	fs.SetOutput(&out)
+	fs.Usage()
+	expected := NewHelp(strings.NewReader(out.String()))
+	if err := expected.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	actual := FromFlagSet(fs)
+	if !reflect.DeepEqual(expected.Flags, actual.Flags) {
+		t.Fatalf("expected:\n%v\ngot:\n%v", expected.Flags, actual.Flags)
+	}
+}
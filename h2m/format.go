@@ -0,0 +1,134 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format is an output format of a man page.
+type Format string
+
+const (
+	// FormatMan is the man(7) roff format, as written by [Write].
+	FormatMan Format = "man"
+	// FormatMarkdown is the CommonMark format.
+	FormatMarkdown Format = "markdown"
+	// FormatHTML is a standalone HTML page.
+	FormatHTML Format = "html"
+	// FormatMdoc is the mdoc(7) semantic roff format.
+	FormatMdoc Format = "mdoc"
+	// FormatJSON is the parsed model of the man page, rather than its
+	// text, to inspect it or use it in other generators. Its schema is
+	// versioned by [JSONVersion]:
+	//
+	//	{
+	//		"version": 1,
+	//		"page": {"name", "description", "version", "section", "manual"},
+	//		"help": {
+	//			"usage": ["prog [OPTION]... FILE", ...],
+	//			"flags": [{"names": ["-o", "--output"], "arg", "type", "bool", "default", "usage"}, ...],
+	//			"commands": [{"names": ["name", "alias", ...], "usage", "help": {...} | null}, ...],
+	//			"sections": [{"title", "position", "text"}, ...],
+	//			"references": ["page(1)", ...]
+	//		},
+	//		"include": {
+	//			"sections": [{"title", "position": "" | "before" | "replace" | "after", "text"}, ...],
+	//			"patterns": [{"regexp", "text", "matched"}, ...],
+	//			"options": [{"name", "value", "line"}, ...]
+	//		} | null
+	//	}
+	//
+	// The fields without a value are strings, except "bool" and "matched"
+	// which are booleans and "line" which is a number. The sections are
+	// ordered like in the man page, and "matched" reports whether the
+	// pattern has been inserted in it.
+	FormatJSON Format = "json"
+)
+
+// Formats are the supported output formats.
+var Formats = []Format{FormatMan, FormatMarkdown, FormatHTML, FormatMdoc, FormatJSON}
+
+// Extension returns the file extension of the format, for a man page of the
+// given section. The ones of HTML pages include the section, to match the
+// links of their references.
+func (f Format) Extension(section string) string {
+	switch f {
+	case FormatMarkdown:
+		return "md"
+	case FormatHTML:
+		return section + ".html"
+	case FormatJSON:
+		return "json"
+	default:
+		return section
+	}
+}
+
+// ParseFormat returns the format of the given name.
+func ParseFormat(name string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == name {
+			return f, nil
+		}
+	}
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("unknown format %q, must be one of: %s", name, strings.Join(names, ", "))
+}
+
+// WriteFormat writes in w the man page p made of the given include and help,
+// in the given format. The man page is first written as roff by [Write], then
+// parsed by [ParseRoff] to be converted to other formats, so that the roff in
+// include files is translated too. [FormatJSON] is written from the include
+// and help instead, once the patterns have been inserted.
+func WriteFormat(w io.Writer, format Format, p *Page, include *Include, help *Help) (err error) {
+	if format == FormatMan {
+		return Write(w, p, include, help)
+	}
+	var b strings.Builder
+	if err := Write(&b, p, include, help); err != nil {
+		return err
+	}
+	if format == FormatJSON {
+		return writeJSON(w, p, include, help)
+	}
+	defer func() {
+		if !debugMode {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("%v", p)
+			}
+		}
+	}()
+	d := ParseRoff(b.String())
+	switch format {
+	case FormatMarkdown:
+		writeMarkdown(w, p, d)
+	case FormatHTML:
+		writeHTML(w, p, d)
+	case FormatMdoc:
+		writeMdoc(w, p, d)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+	return
+}
@@ -0,0 +1,185 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+// Package h2m generates man pages from the help output of Go programs, as
+// printed by the "flag" package or other CLI libraries.
+//
+// A [Help] is parsed from the help output of a program with [NewHelp] and
+// [Help.Parse], or built from a [flag.FlagSet] with [FromFlagSet]. It can be
+// completed by an [Include] parsed from an .h2m include file, then written as
+// a man page with [Write].
+package h2m
+
+import (
+	"debug/buildinfo"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Module is the path of the module of this package.
+const Module = "github.com/n-peugnet/gohelp2man"
+
+const (
+	RegexSection    = `^\[([^]]+)\]\s*$`
+	RegexPattern    = `^/(.*)/([ims]*)\s*$`
+	RegexUsage      = `[Uu]sage(:| of) (?U:(.*)):?$`
+	RegexUsage2     = `^((\t|\s+or: )(.*)|  ([^-].*))$`
+	RegexHeader     = `^(\w.*):\s*$`
+	RegexFlag       = `^  -((\w)\t(.*)|([-\w]+) (.+)|[-\w]+)$`
+	RegexFUsage     = `^  [^-].*$`
+	RegexFlagLike   = `^\s*--?\w`
+	RegexIndented   = `^\s+\S`
+	RegexUrfaveFlag = `^\s+(--?[-\w]+(?: [^\s,]+)?(?:, --?[-\w]+(?: [^\s,]+)?)*)(?:\t|\s{2,})(\S.*?)\s*$`
+	RegexGNUFlag    = `^\s+(?:-(\w),\s+)?--([-\w.]+)(\[=[^]]*\])?([ =]\S+)?(?:\s{2,}(\S.*?))?\s*$`
+	RegexDefault    = `\s*\(default:? (.*)\)$`
+	RegexCommand    = `^\s+(\w[-\w]*)((?:, \w[-\w]*)*)(?:\t+|\s{2,})(\S.*)$`
+)
+
+var (
+	debugMode       = os.Getenv("GOH2M_DEBUG") != ""
+	regexSection    = regexp.MustCompile(RegexSection)
+	regexPattern    = regexp.MustCompile(RegexPattern)
+	regexUsage      = regexp.MustCompile(RegexUsage)
+	regexUsage2     = regexp.MustCompile(RegexUsage2)
+	regexHeader     = regexp.MustCompile(RegexHeader)
+	regexFlag       = regexp.MustCompile(RegexFlag)
+	regexFUsage     = regexp.MustCompile(RegexFUsage)
+	regexFlagLike   = regexp.MustCompile(RegexFlagLike)
+	regexGNUFlag    = regexp.MustCompile(RegexGNUFlag)
+	regexIndented   = regexp.MustCompile(RegexIndented)
+	regexUrfaveFlag = regexp.MustCompile(RegexUrfaveFlag)
+	regexDefault    = regexp.MustCompile(RegexDefault)
+	regexCommand    = regexp.MustCompile(RegexCommand)
+)
+
+// lastSections is the index in KnownSections of the first section written
+// after the other sections.
+const lastSections = 5
+
+var KnownSections = [12]string{
+	"NAME",
+	"SYNOPSIS",
+	"DESCRIPTION",
+	"OPTIONS",
+	"COMMANDS",
+	// Other sections, then the ones from lastSections.
+	"ENVIRONMENT",
+	"FILES",
+	"EXAMPLES",
+	"AUTHOR",
+	"REPORTING BUGS",
+	"COPYRIGHT",
+	"SEE ALSO",
+}
+
+func findKnownSection(s string) (title string, found bool) {
+	title = strings.ToUpper(s)
+	switch title {
+	case "OPTIONS", "FLAGS":
+		title = "OPTIONS"
+		found = true
+	case "COMMANDS", "SUBCOMMANDS":
+		title = "COMMANDS"
+		found = true
+	case "NAME",
+		"SYNOPSIS",
+		"DESCRIPTION",
+		"ENVIRONMENT",
+		"FILES",
+		"EXAMPLES",
+		"AUTHOR",
+		"REPORTING BUGS",
+		"COPYRIGHT",
+		"SEE ALSO":
+		found = true
+	}
+	return
+}
+
+// Version returns the current version of gohelp2man as found in build info,
+// either as the main module or as a dependency.
+func Version() string {
+	v := "(unknown)"
+	info, ok := debug.ReadBuildInfo()
+	if ok {
+		v = info.Main.Version
+		for _, dep := range info.Deps {
+			if dep.Path == Module && info.Main.Path != Module {
+				v = dep.Version
+			}
+		}
+	}
+	return v
+}
+
+// ExecutableVersion returns the version of the Go executable at path, as found
+// in its build info: the version of its main module or, for development builds,
+// its VCS revision.
+func ExecutableVersion(path string) (string, error) {
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	v := buildInfoVersion(info)
+	if v == "" {
+		return "", fmt.Errorf("%s: no version in build info", path)
+	}
+	return v, nil
+}
+
+// buildInfoVersion returns the version of the main module of info or, for
+// development builds, its short VCS revision suffixed by "-dirty" if it was
+// modified. It returns an empty string if there is none.
+func buildInfoVersion(info *debug.BuildInfo) string {
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	var revision, modified string
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value
+		}
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if revision != "" && modified == "true" {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// now returns the current time or the value of SOURCE_DATE_EPOCH if defined.
+func now() time.Time {
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		unixEpoch, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			panic("invalid SOURCE_DATE_EPOCH: " + err.Error())
+		}
+		return time.Unix(unixEpoch, 0)
+	} else {
+		return time.Now()
+	}
+}
@@ -0,0 +1,69 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestExecutableVersion(t *testing.T) {
+	if _, err := ExecutableVersion("testdata/static/main.go"); err == nil {
+		t.Fatal("expected an error for a source file")
+	}
+
+	exe := filepath.Join(t.TempDir(), "static")
+	cmd := exec.Command("go", "build", "-buildvcs=false", "-o", exe, "./testdata/static")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s: %v\n%s", cmd, err, out)
+	}
+	_, err := ExecutableVersion(exe)
+	if err == nil || !strings.Contains(err.Error(), "no version in build info") {
+		t.Fatalf("expected no version error, got %v", err)
+	}
+}
+
+func TestBuildInfoVersion(t *testing.T) {
+	revision := debug.BuildSetting{Key: "vcs.revision", Value: "0123456789abcdef0123"}
+	cases := []struct {
+		name     string
+		info     *debug.BuildInfo
+		expected string
+	}{
+		{"module", &debug.BuildInfo{Main: debug.Module{Version: "v1.2.3"}, Settings: []debug.BuildSetting{revision}}, "v1.2.3"},
+		{"revision", &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}, Settings: []debug.BuildSetting{
+			revision,
+			{Key: "vcs.modified", Value: "false"},
+		}}, "0123456789ab"},
+		{"dirty", &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}, Settings: []debug.BuildSetting{
+			revision,
+			{Key: "vcs.modified", Value: "true"},
+		}}, "0123456789ab-dirty"},
+		{"none", &debug.BuildInfo{Settings: []debug.BuildSetting{{Key: "vcs.modified", Value: "true"}}}, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := buildInfoVersion(c.info); actual != c.expected {
+				t.Fatalf("expected %q, got %q", c.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,671 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+type Section struct {
+	Title string
+	Text  string
+	Pos   byte
+}
+
+func (s *Section) String() string {
+	return fmt.Sprintf("{%q %q %q}", s.Title, s.Text, s.Pos)
+}
+
+// Warning is a likely mistake found while parsing a help output or an
+// include file, which did not prevent it.
+type Warning struct {
+	// Line is the number of the line of the warning, starting at 1.
+	Line    int
+	Message string
+}
+
+func (w *Warning) String() string {
+	return fmt.Sprintf("line %d: %s", w.Line, w.Message)
+}
+
+// Syntaxes of the flags in help outputs.
+const (
+	// SyntaxAuto recognises both SyntaxGo and SyntaxGNU flags.
+	SyntaxAuto = "auto"
+	// SyntaxGo is the syntax of the "flag" package.
+	SyntaxGo = "go"
+	// SyntaxGNU is the syntax of GNU programs and of the
+	// "github.com/spf13/pflag" package: "  -o, --output string   usage".
+	SyntaxGNU = "gnu"
+	// SyntaxUrfave is the syntax of the "github.com/urfave/cli" package:
+	// "   --output value, -o value  usage".
+	SyntaxUrfave = "urfave"
+)
+
+// Dialect describes the help output of a CLI library.
+type Dialect struct {
+	// Syntax is the syntax of the flags.
+	Syntax string
+	// Headers maps upper cased headers to the title of the known section
+	// they introduce, in addition to the ones of findKnownSection. The
+	// indented lines following a "SYNOPSIS" header are usage lines.
+	Headers map[string]string
+	// EndSectionOnBlank makes blank lines end the known sections, the
+	// text that follows going back to DESCRIPTION.
+	EndSectionOnBlank bool
+	// TrimIndent removes the indentation of the sections text.
+	TrimIndent bool
+}
+
+// section returns the title of the known section introduced by header.
+func (d *Dialect) section(header string) (title string, found bool) {
+	if title, found = d.Headers[strings.ToUpper(header)]; found {
+		return
+	}
+	return findKnownSection(header)
+}
+
+// Dialects are the known dialects, by name. The default is "flag".
+var Dialects = map[string]*Dialect{
+	"cobra": {
+		Syntax: SyntaxGNU,
+		Headers: map[string]string{
+			"USAGE":                  "SYNOPSIS",
+			"AVAILABLE COMMANDS":     "COMMANDS",
+			"ADDITIONAL COMMANDS":    "COMMANDS",
+			"ADDITIONAL HELP TOPICS": "COMMANDS",
+			"GLOBAL FLAGS":           "OPTIONS",
+		},
+		EndSectionOnBlank: true,
+	},
+	"flag": {
+		Syntax: SyntaxAuto,
+	},
+	"urfave": {
+		Syntax: SyntaxUrfave,
+		Headers: map[string]string{
+			"USAGE":          "SYNOPSIS",
+			"GLOBAL OPTIONS": "OPTIONS",
+			"AUTHORS":        "AUTHOR",
+		},
+		EndSectionOnBlank: true,
+		TrimIndent:        true,
+	},
+}
+
+// FlagTypes are the argument names printed by the flag and pflag packages
+// when the usage of a flag does not name its argument.
+var FlagTypes = map[string]bool{
+	"bools":       true,
+	"count":       true,
+	"duration":    true,
+	"float":       true,
+	"float32":     true,
+	"int":         true,
+	"int32":       true,
+	"ints":        true,
+	"ip":          true,
+	"string":      true,
+	"stringArray": true,
+	"strings":     true,
+	"uint":        true,
+	"uint32":      true,
+	"uints":       true,
+	"value":       true,
+}
+
+type Flag struct {
+	// Name is the name of the flag without its leading dash. GNU long
+	// options thus keep one of their two leading dashes (e.g. "-output").
+	Name string
+	// Arg is the name of the argument of the flag. It starts with its
+	// separator when it is attached to the name, e.g. "=N" for "--count=N"
+	// or "[=WHEN]" for an optional argument.
+	Arg   string
+	Usage string
+
+	// Default is the default value as printed at the end of Usage, with
+	// quotes for strings.
+	Default string
+	// Type is the type of the argument, if Arg is one of FlagTypes.
+	Type   string
+	IsBool bool
+	// Aliases are the other names of this flag.
+	Aliases []string
+}
+
+func (f *Flag) String() string {
+	return fmt.Sprintf("-%s %q: %s (default %s, type %q, bool %v, aliases %v)", f.Name, f.Arg, f.Usage, f.Default, f.Type, f.IsBool, f.Aliases)
+}
+
+// merge adds the names of o as aliases of f. The details of o are used if f
+// has no usage. The shortest name is kept as the name of f.
+func (f *Flag) merge(o *Flag) {
+	if f.Usage == "" {
+		f.Usage, f.Default = o.Usage, o.Default
+	}
+	if f.Arg == "" {
+		f.Arg, f.Type, f.IsBool = o.Arg, o.Type, o.IsBool
+	}
+	names := append([]string{f.Name}, f.Aliases...)
+	names = append(names, o.Name)
+	names = append(names, o.Aliases...)
+	sort.SliceStable(names, func(i, j int) bool { return len(names[i]) < len(names[j]) })
+	f.Name, f.Aliases = names[0], names[1:]
+}
+
+// isShort reports whether f is named by a single letter. As merge keeps the
+// shortest name, its aliases do not need to be checked.
+func (f *Flag) isShort() bool {
+	return len(strings.TrimLeft(f.Name, "-")) == 1
+}
+
+// parseDetails sets the default value and the type of f from its argument
+// and usage. A "(default is ...)" written in the usage, as often done with
+// cobra, is prose rather than a default value and is kept as is.
+func (f *Flag) parseDetails() {
+	if m := regexDefault.FindStringSubmatch(f.Usage); m != nil && !strings.HasPrefix(m[1], "is ") {
+		f.Default = m[1]
+	}
+	if FlagTypes[f.argName()] {
+		f.Type = f.argName()
+	}
+	f.IsBool = f.Arg == ""
+}
+
+// argName returns the name of the argument of f, without its "=" separator
+// nor the brackets of an optional argument, e.g. "WHEN" for "[=WHEN]".
+func (f *Flag) argName() string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(f.Arg, "["), "="), "]")
+}
+
+// argSuffix returns the argument of f as written after its names: separated
+// by a space, unless it starts with its own separator, e.g. "=N".
+func (f *Flag) argSuffix() string {
+	if f.Arg == "" || strings.HasPrefix(f.Arg, "=") || strings.HasPrefix(f.Arg, "[=") {
+		return f.Arg
+	}
+	return " " + f.Arg
+}
+
+// usageWithoutDefault returns the usage of f, without its default value.
+func (f *Flag) usageWithoutDefault() string {
+	if f.Default == "" {
+		return f.Usage
+	}
+	return strings.TrimSpace(regexDefault.ReplaceAllString(f.Usage, ""))
+}
+
+// Command is a subcommand of a program. Help is only set if the help output
+// of the subcommand has been retrieved.
+type Command struct {
+	Name    string
+	Aliases []string
+	Usage   string
+	Help    *Help
+}
+
+func (c *Command) String() string {
+	return fmt.Sprintf("%s %v: %s", c.Name, c.Aliases, c.Usage)
+}
+
+type Help struct {
+	// Dialect is the dialect of the help output, "flag" if nil.
+	Dialect *Dialect
+	// Syntax is the syntax of the flags to recognise. If empty, the one of
+	// Dialect is used.
+	Syntax string
+
+	Usage    string
+	Flags    []*Flag
+	Commands []*Command
+	Sections map[string]*Section
+
+	// SeparateDefaults makes the default values of the flags written on
+	// their own line instead of at the end of their usage.
+	SeparateDefaults bool
+	// References are man pages, like "tool-build(1)", that are appended
+	// to the SEE ALSO section.
+	References []string
+	// Lines are the lines of the help output, as classified by Parse.
+	Lines []*HelpLine
+	// Warnings are the likely mistakes of the help output found by Parse.
+	Warnings []*Warning
+
+	scanner *bufio.Scanner
+	// line is the number of the current line of scanner.
+	line int
+	// eof is true once scanner has no more lines.
+	eof bool
+}
+
+// Kinds of the lines of a help output, see [HelpLine].
+const (
+	LineUsage        = "usage"
+	LineContinuation = "continuation"
+	LineFlag         = "flag"
+	LineFlagUsage    = "flag usage"
+	LineCommand      = "command"
+	LineHeader       = "header"
+	LineText         = "section text"
+)
+
+// HelpLine is a line of a help output, with the way it has been parsed.
+type HelpLine struct {
+	// Number is the number of the line, starting at 1.
+	Number int
+	Text   string
+	// Kind is the classification of the line, one of the Line constants.
+	Kind string
+	// Section is the title of the section of the man page where the line
+	// ended.
+	Section string
+}
+
+func (l *HelpLine) String() string {
+	return fmt.Sprintf("%d %s %s: %q", l.Number, l.Kind, l.Section, l.Text)
+}
+
+// scan advances the internal reader to the next line, like
+// [bufio.Scanner.Scan].
+func (h *Help) scan() bool {
+	if !h.scanner.Scan() {
+		h.eof = true
+		return false
+	}
+	h.line++
+	return true
+}
+
+// classify records the kind of the current line and the section where it
+// ended.
+func (h *Help) classify(kind, section string) {
+	if h.eof {
+		return
+	}
+	h.Lines = append(h.Lines, &HelpLine{
+		Number:  h.line,
+		Text:    h.scanner.Text(),
+		Kind:    kind,
+		Section: section,
+	})
+}
+
+// warn adds a warning about the given line.
+func (h *Help) warn(line int, format string, a ...any) {
+	h.Warnings = append(h.Warnings, &Warning{Line: line, Message: fmt.Sprintf(format, a...)})
+}
+
+// Explain writes in w the lines of the help output classified by Parse, one
+// per line, with their number, kind, section and quoted text.
+func (h *Help) Explain(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, l := range h.Lines {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%q\n", l.Number, l.Kind, l.Section, l.Text)
+	}
+	return tw.Flush()
+}
+
+// dialect returns the dialect of h.
+func (h *Help) dialect() *Dialect {
+	if h.Dialect == nil {
+		return Dialects["flag"]
+	}
+	return h.Dialect
+}
+
+// syntax returns the syntax of the flags of h.
+func (h *Help) syntax() string {
+	if h.Syntax == "" {
+		return h.dialect().Syntax
+	}
+	return h.Syntax
+}
+
+func NewHelp(help io.Reader) *Help {
+	return &Help{
+		Sections: make(map[string]*Section),
+		scanner:  bufio.NewScanner(help),
+	}
+}
+
+// parseUsage parses synopsis lines from the internal reader. It will continue
+// until the current line does not look like a synopsis/usage string, leaving
+// the current line to be parsed.
+func (h *Help) parseUsage() {
+	var text strings.Builder
+	line := h.scanner.Bytes()
+	m := regexUsage.FindSubmatch(line)
+	if m != nil {
+		h.classify(LineUsage, "SYNOPSIS")
+		if bytes.IndexRune(m[2], ' ') != -1 {
+			text.Write(m[2])
+		}
+		for h.scan() {
+			m = regexUsage2.FindSubmatch(h.scanner.Bytes())
+			if m != nil {
+				h.classify(LineContinuation, "SYNOPSIS")
+				text.WriteString("\n")
+				text.Write(bytes.TrimSpace(m[3]))
+				text.Write(bytes.TrimSpace(m[4]))
+			} else {
+				break
+			}
+		}
+		h.Usage = strings.TrimSpace(text.String())
+	}
+}
+
+// parseUsageLines parses the indented synopsis lines following a usage
+// header. It will continue until the current line is not indented, leaving it
+// to be parsed.
+func (h *Help) parseUsageLines() {
+	var lines []string
+	for regexIndented.MatchString(h.scanner.Text()) {
+		h.classify(LineUsage, "SYNOPSIS")
+		lines = append(lines, strings.TrimSpace(h.scanner.Text()))
+		if !h.scan() {
+			break
+		}
+	}
+	if len(lines) != 0 {
+		h.Usage = strings.Join(lines, "\n")
+	}
+}
+
+// parseFlag parses a flag in the current line. If returns (nil, false) if the
+// line does not match.
+func (h *Help) parseFlag() (f *Flag, found bool) {
+	line := h.scanner.Text()
+	switch h.syntax() {
+	case SyntaxUrfave:
+		return parseUrfaveFlag(line)
+	case SyntaxGNU:
+		return parseGNUFlag(line)
+	case SyntaxAuto:
+		if f, found = parseGNUFlag(line); found {
+			return
+		}
+	}
+	m := regexFlag.FindStringSubmatch(line)
+	found = m != nil
+	if found {
+		f = new(Flag)
+		switch {
+		case m[2] != "": // short flag
+			f.Name = m[2]
+			f.Usage = m[3]
+			return
+		case m[4] != "": // flag with arg
+			f.Name = m[4]
+			f.Arg = m[5]
+		default:
+			f.Name = m[1]
+		}
+	}
+	return
+}
+
+// parseGNUFlag parses a GNU style flag in line. It returns (nil, false) if
+// the line does not match.
+func parseGNUFlag(line string) (f *Flag, found bool) {
+	m := regexGNUFlag.FindStringSubmatch(line)
+	if m == nil {
+		return nil, false
+	}
+	f = &Flag{
+		Name:  "-" + m[2],
+		Arg:   m[3] + strings.TrimPrefix(m[4], " "),
+		Usage: m[5],
+	}
+	if m[1] != "" {
+		f.Name, f.Aliases = m[1], []string{f.Name}
+	}
+	return f, true
+}
+
+// parseUrfaveFlag parses a urfave/cli style flag in line. It returns
+// (nil, false) if the line does not match.
+func parseUrfaveFlag(line string) (f *Flag, found bool) {
+	m := regexUrfaveFlag.FindStringSubmatch(line)
+	if m == nil {
+		return nil, false
+	}
+	f = &Flag{Usage: m[2]}
+	var names []string
+	for _, n := range strings.Split(m[1], ", ") {
+		n, arg, _ := strings.Cut(n, " ")
+		names = append(names, strings.TrimPrefix(n, "-"))
+		if f.Arg == "" {
+			f.Arg = arg
+		}
+	}
+	sort.SliceStable(names, func(i, j int) bool { return len(names[i]) < len(names[j]) })
+	f.Name = names[0]
+	if len(names) > 1 {
+		f.Aliases = names[1:]
+	}
+	return f, true
+}
+
+// parseFlags parses flags from the internal reader. It will continue until
+// the current line does not look like a flag or a flag description, leaving
+// the current line to be parsed.
+func (h *Help) parseFlags() {
+	for {
+		if f, found := h.parseFlag(); found {
+			h.classify(LineFlag, "OPTIONS")
+			line := h.line
+			var text strings.Builder
+			if f.Usage != "" {
+				text.WriteString(f.Usage)
+			}
+			h.Flags = append(h.Flags, f)
+			for h.scan() {
+				line := h.scanner.Text()
+				if regexFUsage.MatchString(line) && !h.isFlag(line) {
+					h.classify(LineFlagUsage, "OPTIONS")
+					text.WriteString("\n")
+					text.WriteString(strings.TrimSpace(line))
+				} else {
+					break
+				}
+			}
+			f.Usage = strings.TrimSpace(text.String())
+			f.parseDetails()
+			if f.Usage == "" {
+				h.warn(line, "option %s has no usage", strings.Join(f.names(), ", "))
+			}
+		} else {
+			break
+		}
+	}
+}
+
+// isFlag reports whether line is a GNU or urfave/cli style flag. Unlike the
+// flags of the "flag" package, they can be indented enough to look like a flag
+// usage.
+func (h *Help) isFlag(line string) bool {
+	switch h.syntax() {
+	case SyntaxGo:
+		return false
+	case SyntaxUrfave:
+		return regexUrfaveFlag.MatchString(line)
+	default:
+		return regexGNUFlag.MatchString(line)
+	}
+}
+
+// parseCommands parses subcommands from the internal reader. It will continue
+// until the current line does not look like a command, leaving the current
+// line to be parsed.
+func (h *Help) parseCommands() {
+	for {
+		m := regexCommand.FindStringSubmatch(h.scanner.Text())
+		if m == nil {
+			break
+		}
+		h.classify(LineCommand, "COMMANDS")
+		c := &Command{Name: m[1], Usage: m[3]}
+		if m[2] != "" {
+			c.Aliases = strings.Split(strings.TrimPrefix(m[2], ", "), ", ")
+		}
+		h.Commands = append(h.Commands, c)
+		if !h.scan() {
+			break
+		}
+	}
+}
+
+// Command returns the subcommand with the given name, adding it if needed.
+func (h *Help) Command(name string) *Command {
+	for _, c := range h.Commands {
+		if c.Name == name {
+			return c
+		}
+	}
+	c := &Command{Name: name}
+	h.Commands = append(h.Commands, c)
+	return c
+}
+
+// GroupFlags merges the flags declared as aliases of each other, and if auto
+// is true, the flags that share the same argument and usage when one of them
+// is named by a single letter, so that long flags with the same boilerplate
+// usage (e.g. "Deprecated.") are kept apart. Merged flags take the position
+// of the first one. The names of aliases are compared to
+// the names and aliases of the flags without their leading dashes, so that
+// "verbose" matches both "-verbose" and "--verbose".
+func (h *Help) GroupFlags(aliases [][]string, auto bool) {
+	groups := make(map[string]int)
+	for i, names := range aliases {
+		for _, n := range names {
+			groups[strings.TrimLeft(n, "-")] = i
+		}
+	}
+	group := func(f *Flag) (int, bool) {
+		for _, n := range append([]string{f.Name}, f.Aliases...) {
+			if i, found := groups[strings.TrimLeft(n, "-")]; found {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+	leaders := make(map[int]*Flag)
+	byUsage := make(map[string]*Flag)
+	var flags []*Flag
+	for _, f := range h.Flags {
+		var leader *Flag
+		if i, found := group(f); found {
+			leader = leaders[i]
+			if leader == nil {
+				leaders[i] = f
+			}
+		} else if auto && f.Usage != "" {
+			key := f.argName() + "\n" + f.Usage
+			if l := byUsage[key]; l == nil {
+				byUsage[key] = f
+			} else if l.isShort() || f.isShort() {
+				leader = l
+			}
+		}
+		if leader != nil {
+			leader.merge(f)
+		} else {
+			flags = append(flags, f)
+		}
+	}
+	h.Flags = flags
+}
+
+func (h *Help) parseHeader() (header string, found bool) {
+	line := h.scanner.Text()
+	m := regexHeader.FindStringSubmatch(line)
+	if m != nil {
+		return m[1], true
+	}
+	return "", false
+}
+
+// Parse parses the help message from the reader given to [NewHelp]. The text
+// of known sections found multiple times is joined in different paragraphs.
+// The way each line has been parsed is recorded in Lines, and the likely
+// mistakes in Warnings.
+func (h *Help) Parse() error {
+	d := h.dialect()
+	var s *Section = &Section{Title: "DESCRIPTION"}
+	var text strings.Builder
+	// header is the line number of the header of s, the text before the
+	// first header going to DESCRIPTION without one.
+	header := 0
+	finaliseSection := func() {
+		s.Text = strings.TrimSpace(text.String())
+		if prev, found := h.Sections[s.Title]; found && s.Text != "" {
+			if s.Title != "DESCRIPTION" {
+				h.warn(header, "duplicate section %s, joined with the previous one", s.Title)
+			}
+			prev.Text += "\n\n" + s.Text
+		} else if s.Text != "" {
+			h.Sections[s.Title] = s
+		}
+		text.Reset()
+	}
+
+	for h.scan() {
+		h.parseUsage()
+		if s.Title == "SYNOPSIS" {
+			h.parseUsageLines()
+			s = &Section{Title: "DESCRIPTION"}
+		}
+		h.parseFlags()
+		if s.Title == "COMMANDS" {
+			h.parseCommands()
+		}
+		if hr, found := h.parseHeader(); found {
+			if title, found := d.section(hr); found {
+				h.classify(LineHeader, title)
+				finaliseSection()
+				s = &Section{Title: title}
+				header = h.line
+				continue
+			}
+		}
+		line := h.scanner.Text()
+		if d.EndSectionOnBlank && s.Title != "DESCRIPTION" && strings.TrimSpace(line) == "" {
+			finaliseSection()
+			s = &Section{Title: "DESCRIPTION"}
+		}
+		h.classify(LineText, s.Title)
+		if regexFlagLike.MatchString(line) {
+			h.warn(h.line, "line looks like an option but is not parsed as one: %q", line)
+		}
+		if d.TrimIndent {
+			line = strings.TrimSpace(line)
+		}
+		text.WriteString(line)
+		text.WriteString("\n")
+	}
+	finaliseSection()
+	return h.scanner.Err()
+}
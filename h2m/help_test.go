@@ -0,0 +1,663 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseUsage(t *testing.T) {
+	cases := []struct {
+		name  string
+		val   string
+		usage string
+	}{
+		{
+			"empty string",
+			"",
+			"",
+		},
+		{
+			"short flag",
+			"  -h	Show help and exit.",
+			"",
+		},
+		{
+			"go flag default",
+			"Usage of gohelp2man:",
+			"",
+		},
+		{
+			"custom GNU-like",
+			"Usage: gohelp2man [OPTION]... EXECUTABLE",
+			"gohelp2man [OPTION]... EXECUTABLE",
+		},
+		{
+			"multiline GNU-like",
+			`Usage: ln [OPTION]... [-T] TARGET LINK_NAME
+  or:  ln [OPTION]... TARGET
+  or:  ln [OPTION]... TARGET... DIRECTORY
+  or:  ln [OPTION]... -t DIRECTORY TARGET...
+In the 1st form, create a link to TARGET with the name LINK_NAME.`,
+			`ln [OPTION]... [-T] TARGET LINK_NAME
+ln [OPTION]... TARGET
+ln [OPTION]... TARGET... DIRECTORY
+ln [OPTION]... -t DIRECTORY TARGET...`,
+		},
+		{
+			"multiline go-like",
+			`Usage of stringer:
+	stringer [flags] -type T [directory]
+	stringer [flags] -type T files... # Must be a single package
+For more information, see:
+	https://pkg.go.dev/golang.org/x/tools/cmd/stringer`,
+			`stringer [flags] -type T [directory]
+stringer [flags] -type T files... # Must be a single package`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			help := NewHelp(strings.NewReader(c.val))
+			help.scanner.Scan()
+			help.parseUsage()
+			if !reflect.DeepEqual(c.usage, help.Usage) {
+				t.Fatalf("expected:\n%v\ngot:\n%v", c.usage, help.Usage)
+			}
+		})
+	}
+}
+
+func TestParseUsageLines(t *testing.T) {
+	cases := []struct {
+		name     string
+		previous string
+		val      string
+		usage    string
+	}{
+		{"indented", "", "  prog FILE\n  prog -v\nText.", "prog FILE\nprog -v"},
+		{"replace previous", "prog [OPTION]...", "\tprog FILE", "prog FILE"},
+		{"keep previous", "prog [OPTION]...", "Text.", "prog [OPTION]..."},
+		{"empty", "prog [OPTION]...", "", "prog [OPTION]..."},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			help := NewHelp(strings.NewReader(c.val))
+			help.Usage = c.previous
+			help.scan()
+			help.parseUsageLines()
+			if c.usage != help.Usage {
+				t.Fatalf("expected:\n%v\ngot:\n%v", c.usage, help.Usage)
+			}
+		})
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	cases := []struct {
+		name  string
+		val   string
+		flag  *Flag
+		found bool
+	}{
+		{
+			"empty string",
+			"",
+			nil,
+			false,
+		},
+		{
+			"synopsis",
+			"Usage of compose-spec:",
+			nil,
+			false,
+		},
+		{
+			"simple short",
+			"  -h	Show help and exit.",
+			&Flag{"h", "", "Show help and exit.", "", "", true, nil},
+			true,
+		},
+		{
+			"multi short",
+			`  -h	Show help
+    	and exit.`,
+			&Flag{"h", "", "Show help\nand exit.", "", "", true, nil},
+			true,
+		},
+		{
+			"simple long",
+			`  -help
+    	Show help and exit.`,
+			&Flag{"help", "", "Show help and exit.", "", "", true, nil},
+			true,
+		},
+		{
+			"multi long",
+			`  -help
+    	Show help
+    	and exit.`,
+			&Flag{"help", "", "Show help\nand exit.", "", "", true, nil},
+			true,
+		},
+		{
+			"simple arg",
+			`  -fmt string
+    	Output format (yaml|json). (default "yaml")`,
+			&Flag{"fmt", "string", `Output format (yaml|json). (default "yaml")`, `"yaml"`, "string", false, nil},
+			true,
+		},
+		{
+			"kebab case",
+			`  -kebab-case
+    	Flag using kebab case.`,
+			&Flag{"kebab-case", "", "Flag using kebab case.", "", "", true, nil},
+			true,
+		},
+		{
+			"single digit",
+			"  -6	Use IPv6 protocol.",
+			&Flag{"6", "", "Use IPv6 protocol.", "", "", true, nil},
+			true,
+		},
+		{
+			"short with custom arg",
+			`  -t V
+    	Use V as test. (default "test")`,
+			&Flag{"t", "V", `Use V as test. (default "test")`, `"test"`, "", false, nil},
+			true,
+		},
+		{
+			"duration with default",
+			`  -timeout duration
+    	Stop after this duration. (default 1m0s)`,
+			&Flag{"timeout", "duration", "Stop after this duration. (default 1m0s)", "1m0s", "duration", false, nil},
+			true,
+		},
+		{
+			"custom arg with space",
+			`  -test V V
+    	Use V V as test. (default "test")
+`,
+			&Flag{"test", "V V", `Use V V as test. (default "test")`, `"test"`, "", false, nil},
+			true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			help := NewHelp(strings.NewReader(c.val))
+			help.scanner.Scan()
+			help.parseFlags()
+			if c.found {
+				if len(help.Flags) == 0 {
+					t.Fatal("expected to get one flag, got none")
+				}
+				f := help.Flags[0]
+				if !reflect.DeepEqual(c.flag, f) {
+					t.Fatalf("expected:\n%v\ngot:\n%v", c.flag, f)
+				}
+			} else {
+				if len(help.Flags) != 0 {
+					t.Fatal("expected to get no flags, got ", help.Flags)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFlagsSyntax(t *testing.T) {
+	cases := []struct {
+		name   string
+		val    string
+		flag   *Flag
+		found  bool
+		syntax string
+	}{
+		{
+			"gnu short and long",
+			`  -o, --output string   Write to this file. (default "-")
+      --verbose         Be verbose.`,
+			&Flag{"o", "string", `Write to this file. (default "-")`, `"-"`, "string", false, []string{"-output"}},
+			true,
+			SyntaxAuto,
+		},
+		{
+			"gnu long only multiline",
+			`      --verbose         Be verbose,
+                        very verbose.
+      --other           Other flag.`,
+			&Flag{"-verbose", "", "Be verbose,\nvery verbose.", "", "", true, nil},
+			true,
+			SyntaxAuto,
+		},
+		{
+			"gnu default is",
+			`      --config string   config file (default is hugo.yaml|json|toml)`,
+			&Flag{"-config", "string", "config file (default is hugo.yaml|json|toml)", "", "string", false, nil},
+			true,
+			SyntaxAuto,
+		},
+		{
+			"gnu equal arg",
+			`  -o, --output=FILE  Write to FILE.`,
+			&Flag{"o", "=FILE", "Write to FILE.", "", "", false, []string{"-output"}},
+			true,
+			SyntaxAuto,
+		},
+		{
+			"gnu equal typed arg",
+			`  --count=int  Repeat int times.`,
+			&Flag{"-count", "=int", "Repeat int times.", "", "int", false, nil},
+			true,
+			SyntaxGNU,
+		},
+		{
+			"gnu optional arg",
+			`  --color[=WHEN]  Colorize the output.`,
+			&Flag{"-color", "[=WHEN]", "Colorize the output.", "", "", false, nil},
+			true,
+			SyntaxGNU,
+		},
+		{
+			"gnu usage on next line",
+			`  --output FILE
+    Write to FILE.`,
+			&Flag{"-output", "FILE", "Write to FILE.", "", "", false, nil},
+			true,
+			SyntaxGNU,
+		},
+		{
+			"gnu with go syntax",
+			`  -o, --output string   Write to this file.`,
+			nil,
+			false,
+			SyntaxGo,
+		},
+		{
+			"go with gnu syntax",
+			"  -h	Show help and exit.",
+			nil,
+			false,
+			SyntaxGNU,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			help := NewHelp(strings.NewReader(c.val))
+			help.Syntax = c.syntax
+			help.scanner.Scan()
+			help.parseFlags()
+			if !c.found {
+				if len(help.Flags) != 0 {
+					t.Fatal("expected to get no flags, got ", help.Flags)
+				}
+				return
+			}
+			if len(help.Flags) == 0 {
+				t.Fatal("expected to get one flag, got none")
+			}
+			if !reflect.DeepEqual(c.flag, help.Flags[0]) {
+				t.Fatalf("expected:\n%v\ngot:\n%v", c.flag, help.Flags[0])
+			}
+		})
+	}
+}
+
+func TestGroupFlags(t *testing.T) {
+	flags := func() []*Flag {
+		return []*Flag{
+			{Name: "n", IsBool: true},
+			{Name: "o", Arg: "FILE", Usage: "Write to FILE."},
+			{Name: "output", Arg: "FILE", Usage: "Write to FILE."},
+			{Name: "dry-run", Usage: "Do nothing.", IsBool: true},
+			{Name: "q", Usage: "Be quiet.", IsBool: true},
+			{Name: "v", Usage: "Be quiet.", IsBool: true},
+			{Name: "s", Usage: "Be silent.", IsBool: true, Aliases: []string{"-silent"}},
+			{Name: "-mute", Usage: "Mute.", IsBool: true},
+			{Name: "old", Usage: "Deprecated.", IsBool: true},
+			{Name: "older", Usage: "Deprecated.", IsBool: true},
+		}
+	}
+	cases := []struct {
+		name     string
+		aliases  [][]string
+		auto     bool
+		expected []*Flag
+	}{
+		{"none", nil, false, flags()},
+		{
+			"auto",
+			nil,
+			true,
+			[]*Flag{
+				{Name: "n", IsBool: true},
+				{Name: "o", Arg: "FILE", Usage: "Write to FILE.", Aliases: []string{"output"}},
+				{Name: "dry-run", Usage: "Do nothing.", IsBool: true},
+				{Name: "q", Usage: "Be quiet.", IsBool: true, Aliases: []string{"v"}},
+				{Name: "s", Usage: "Be silent.", IsBool: true, Aliases: []string{"-silent"}},
+				{Name: "-mute", Usage: "Mute.", IsBool: true},
+				{Name: "old", Usage: "Deprecated.", IsBool: true},
+				{Name: "older", Usage: "Deprecated.", IsBool: true},
+			},
+		},
+		{
+			"explicit",
+			[][]string{{"dry-run", "n"}, {"q", "quiet"}},
+			false,
+			[]*Flag{
+				{Name: "n", Usage: "Do nothing.", IsBool: true, Aliases: []string{"dry-run"}},
+				{Name: "o", Arg: "FILE", Usage: "Write to FILE."},
+				{Name: "output", Arg: "FILE", Usage: "Write to FILE."},
+				{Name: "q", Usage: "Be quiet.", IsBool: true},
+				{Name: "v", Usage: "Be quiet.", IsBool: true},
+				{Name: "s", Usage: "Be silent.", IsBool: true, Aliases: []string{"-silent"}},
+				{Name: "-mute", Usage: "Mute.", IsBool: true},
+				{Name: "old", Usage: "Deprecated.", IsBool: true},
+				{Name: "older", Usage: "Deprecated.", IsBool: true},
+			},
+		},
+		{
+			"gnu",
+			[][]string{{"--silent", "-mute"}, {"-n", "--dry-run"}},
+			false,
+			[]*Flag{
+				{Name: "n", Usage: "Do nothing.", IsBool: true, Aliases: []string{"dry-run"}},
+				{Name: "o", Arg: "FILE", Usage: "Write to FILE."},
+				{Name: "output", Arg: "FILE", Usage: "Write to FILE."},
+				{Name: "q", Usage: "Be quiet.", IsBool: true},
+				{Name: "v", Usage: "Be quiet.", IsBool: true},
+				{Name: "s", Usage: "Be silent.", IsBool: true, Aliases: []string{"-mute", "-silent"}},
+				{Name: "old", Usage: "Deprecated.", IsBool: true},
+				{Name: "older", Usage: "Deprecated.", IsBool: true},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			help := &Help{Flags: flags()}
+			help.GroupFlags(c.aliases, c.auto)
+			if !reflect.DeepEqual(c.expected, help.Flags) {
+				t.Fatalf("expected:\n%v\ngot:\n%v", c.expected, help.Flags)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name string
+		val  string
+		help *Help
+		err  string
+	}{
+		{
+			name: "empty string",
+			val:  "",
+			help: &Help{},
+		},
+		{
+			name: "description before usage",
+			val: `A test help message.
+
+Usage: test [OPTION]... ARG
+`,
+			help: &Help{
+				Usage: "test [OPTION]... ARG",
+				Sections: map[string]*Section{
+					"DESCRIPTION": {"DESCRIPTION", "A test help message.", 0},
+				},
+			},
+		},
+		{
+			name: "description after usage",
+			val: `Usage: test [OPTION]... ARG
+
+A test help message.
+`,
+			help: &Help{
+				Usage: "test [OPTION]... ARG",
+				Sections: map[string]*Section{
+					"DESCRIPTION": {"DESCRIPTION", "A test help message.", 0},
+				},
+			},
+		},
+		{
+			name: "description after flags",
+			val: `Usage: test [OPTION]... ARG
+  -h	Show help.
+
+A test help message.
+`,
+			help: &Help{
+				Usage: "test [OPTION]... ARG",
+				Flags: []*Flag{{"h", "", "Show help.", "", "", true, nil}},
+				Sections: map[string]*Section{
+					"DESCRIPTION": {"DESCRIPTION", "A test help message.", 0},
+				},
+			},
+		},
+		{
+			name: "options header",
+			val: `Text of the description.
+
+Options:
+  -h	Show help.
+`,
+			help: &Help{
+				Flags: []*Flag{{"h", "", "Show help.", "", "", true, nil}},
+				Sections: map[string]*Section{
+					"DESCRIPTION": {"DESCRIPTION", "Text of the description.", 0},
+				},
+			},
+		},
+		{
+			name: "unknown section header",
+			val: `Other section:
+Text of this section.
+`,
+			help: &Help{Sections: map[string]*Section{
+				"DESCRIPTION": {"DESCRIPTION", "Other section:\nText of this section.", 0},
+			}},
+		},
+		{
+			name: "commands header",
+			val: `Commands:
+  build   Build the project.
+  version	Print the version.
+Use "test help COMMAND" for more information.
+`,
+			help: &Help{
+				Commands: []*Command{
+					{Name: "build", Usage: "Build the project."},
+					{Name: "version", Usage: "Print the version."},
+				},
+				Sections: map[string]*Section{
+					"COMMANDS": {"COMMANDS", `Use "test help COMMAND" for more information.`, 0},
+				},
+			},
+		},
+		{
+			name: "multiple known headers",
+			val: `Author:
+Nicolas Peugnet
+Author:
+Someone Else
+`,
+			help: &Help{
+				Sections: map[string]*Section{
+					"AUTHOR": {"AUTHOR", "Nicolas Peugnet\n\nSomeone Else", 0},
+				},
+			},
+		},
+		{
+			name: "known header after flags",
+			val: `Text of the description.
+  -h	Show help.
+Author:
+Nicolas Peugnet
+`,
+			help: &Help{
+				Flags: []*Flag{{"h", "", "Show help.", "", "", true, nil}},
+				Sections: map[string]*Section{
+					"DESCRIPTION": {"DESCRIPTION", "Text of the description.", 0},
+					"AUTHOR":      {"AUTHOR", "Nicolas Peugnet", 0},
+				},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			help := NewHelp(strings.NewReader(c.val))
+			err := help.Parse()
+			if c.err != "" {
+				if !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("expected error to contain %q, got %q", c.err, err)
+				}
+				return
+			}
+			if help.Usage != c.help.Usage {
+				t.Errorf("expected usage:\n%v\ngot:\n%v", c.help.Usage, help.Usage)
+			}
+			if !reflect.DeepEqual(c.help.Flags, help.Flags) {
+				t.Errorf("expected flags:\n%v\ngot:\n%v", c.help.Flags, help.Flags)
+			}
+			if !reflect.DeepEqual(c.help.Commands, help.Commands) {
+				t.Errorf("expected commands:\n%v\ngot:\n%v", c.help.Commands, help.Commands)
+			}
+			if c.help.Sections == nil {
+				c.help.Sections = make(map[string]*Section)
+			}
+			if !reflect.DeepEqual(c.help.Sections, help.Sections) {
+				t.Errorf("expected sections:\n%v\ngot:\n%v", c.help.Sections, help.Sections)
+			}
+		})
+	}
+}
+
+func TestParseDialect(t *testing.T) {
+	val := `NAME:
+   test - a test command
+
+USAGE:
+   test [global options] command
+
+COMMANDS:
+   help, h  Shows help
+
+GLOBAL OPTIONS:
+   --output value, -o value  write to this file (default: "-")
+   --help, -h                show help
+
+Text after the options.
+`
+	help := NewHelp(strings.NewReader(val))
+	help.Dialect = Dialects["urfave"]
+	if err := help.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	expected := &Help{
+		Dialect: Dialects["urfave"],
+		Usage:   "test [global options] command",
+		Flags: []*Flag{
+			{"o", "value", `write to this file (default: "-")`, `"-"`, "value", false, []string{"-output"}},
+			{"h", "", "show help", "", "", true, []string{"-help"}},
+		},
+		Commands: []*Command{{Name: "help", Aliases: []string{"h"}, Usage: "Shows help"}},
+		Sections: map[string]*Section{
+			"NAME":        {"NAME", "test - a test command", 0},
+			"DESCRIPTION": {"DESCRIPTION", "Text after the options.", 0},
+		},
+		Lines: []*HelpLine{
+			{1, "NAME:", LineHeader, "NAME"},
+			{2, "   test - a test command", LineText, "NAME"},
+			{3, "", LineText, "DESCRIPTION"},
+			{4, "USAGE:", LineHeader, "SYNOPSIS"},
+			{5, "   test [global options] command", LineUsage, "SYNOPSIS"},
+			{6, "", LineText, "DESCRIPTION"},
+			{7, "COMMANDS:", LineHeader, "COMMANDS"},
+			{8, "   help, h  Shows help", LineCommand, "COMMANDS"},
+			{9, "", LineText, "DESCRIPTION"},
+			{10, "GLOBAL OPTIONS:", LineHeader, "OPTIONS"},
+			{11, `   --output value, -o value  write to this file (default: "-")`, LineFlag, "OPTIONS"},
+			{12, "   --help, -h                show help", LineFlag, "OPTIONS"},
+			{13, "", LineText, "DESCRIPTION"},
+			{14, "Text after the options.", LineText, "DESCRIPTION"},
+		},
+	}
+	help.scanner, help.line, help.eof = nil, 0, false
+	if !reflect.DeepEqual(expected, help) {
+		t.Fatalf("expected:\n%v\ngot:\n%v", expected, help)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	val := `A test program.
+Usage: test [OPTION]...
+  or:  test -h
+  -o FILE
+    	Write to FILE.
+Examples:
+  test -o out
+`
+	help := NewHelp(strings.NewReader(val))
+	if err := help.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := help.Explain(&b); err != nil {
+		t.Fatal(err)
+	}
+	expected := `1  section text  DESCRIPTION  "A test program."
+2  usage         SYNOPSIS     "Usage: test [OPTION]..."
+3  continuation  SYNOPSIS     "  or:  test -h"
+4  flag          OPTIONS      "  -o FILE"
+5  flag usage    OPTIONS      "    \tWrite to FILE."
+6  header        EXAMPLES     "Examples:"
+7  section text  EXAMPLES     "  test -o out"
+`
+	if actual := b.String(); actual != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+func TestParseWarnings(t *testing.T) {
+	val := `Usage of test:
+  -o FILE
+    	Write to FILE.
+  -q
+	-v	Be verbose.
+Environment:
+  TEST_HOME  The home.
+
+Environment:
+  TEST_DEBUG  Debug.
+`
+	help := NewHelp(strings.NewReader(val))
+	if err := help.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []*Warning{
+		{4, "option -q has no usage"},
+		{5, `line looks like an option but is not parsed as one: "\t-v\tBe verbose."`},
+		{9, "duplicate section ENVIRONMENT, joined with the previous one"},
+	}
+	if !reflect.DeepEqual(expected, help.Warnings) {
+		t.Fatalf("expected:\n%v\ngot:\n%v", expected, help.Warnings)
+	}
+}
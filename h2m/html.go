@@ -0,0 +1,279 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const htmlStyle = `body {
+	max-width: 50em;
+	margin: 0 auto;
+	padding: 1em;
+	font-family: sans-serif;
+	line-height: 1.4;
+}
+header, footer {
+	display: flex;
+	justify-content: space-between;
+	color: #666;
+}
+h2 { font-size: 1.1em; margin-top: 1.5em; }
+h3 { font-size: 1em; }
+h2 a, h3 a, dt a.anchor { color: inherit; text-decoration: none; }
+dd, .indent { margin-left: 3em; }
+dd { margin-bottom: 0.6em; }
+pre { background: #f4f4f4; padding: 0.5em; overflow-x: auto; }
+@media (prefers-color-scheme: dark) {
+	body { background: #1e1e1e; color: #ddd; }
+	a { color: #8ab4f8; }
+	pre { background: #2a2a2a; }
+}
+`
+
+var (
+	regexHTMLURL    = regexp.MustCompile(`<(https?://[^\s>]+)>`)
+	regexHTMLManRef = regexp.MustCompile(`^\((\w+)\)`)
+	regexHTMLName   = regexp.MustCompile(`^[\w.:+-]+$`)
+	regexHTMLSlug   = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// htmlSlug returns the given text as a fragment identifier.
+func htmlSlug(text string) string {
+	return strings.Trim(regexHTMLSlug.ReplaceAllString(strings.ToLower(text), "-"), "-")
+}
+
+// htmlText escapes the given text and turns its <URL> into links.
+func htmlText(text string) string {
+	var b strings.Builder
+	pos := 0
+	for _, m := range regexHTMLURL.FindAllStringSubmatchIndex(text, -1) {
+		url := html.EscapeString(text[m[2]:m[3]])
+		b.WriteString(html.EscapeString(text[pos:m[0]]))
+		b.WriteString(`&lt;<a href="` + url + `">` + url + `</a>&gt;`)
+		pos = m[1]
+	}
+	b.WriteString(html.EscapeString(text[pos:]))
+	return b.String()
+}
+
+// htmlInline returns the given line as HTML inline content. The man(1) style
+// references are turned into links to sibling pages, named after the page
+// and its section, e.g. "man.1.html".
+func htmlInline(line Line) string {
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		s := line[i]
+		if s.Break {
+			b.WriteString("<br>\n")
+			continue
+		}
+		text := htmlText(s.Text)
+		switch s.Font {
+		case FontBold:
+			text = "<b>" + text + "</b>"
+		case FontItalic:
+			text = "<i>" + text + "</i>"
+		}
+		if s.Font != FontRoman && i+1 < len(line) && regexHTMLName.MatchString(s.Text) {
+			next := line[i+1]
+			if m := regexHTMLManRef.FindStringSubmatch(next.Text); m != nil && next.Font == FontRoman {
+				href := html.EscapeString(s.Text + "." + m[1] + ".html")
+				b.WriteString(`<a href="` + href + `">` + text + m[0] + `</a>`)
+				b.WriteString(htmlText(next.Text[len(m[0]):]))
+				i++
+				continue
+			}
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+// htmlLines returns the given lines as HTML inline content.
+func htmlLines(lines []Line) string {
+	texts := make([]string, len(lines))
+	for i, l := range lines {
+		texts[i] = htmlInline(l)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// htmlIDs generates unique fragment identifiers.
+type htmlIDs map[string]int
+
+// id returns a unique fragment identifier made of the given text.
+func (ids htmlIDs) id(text string) string {
+	id := htmlSlug(text)
+	ids[id]++
+	if n := ids[id]; n > 1 {
+		id += "-" + strconv.Itoa(n)
+	}
+	return id
+}
+
+// htmlList returns the kind of list of a tagged block, among "ul", "ol" and
+// "dl".
+func htmlList(b *Block) string {
+	tag := b.Tag.String()
+	switch {
+	case tag == "•" || tag == "":
+		return "ul"
+	case regexOrderedTag.MatchString(tag):
+		return "ol"
+	default:
+		return "dl"
+	}
+}
+
+// htmlTagID returns the fragment identifier of a tagged block of the given
+// section, derived from the first word of its tag, e.g. "option-output" for
+// "-o, --output FILE" in OPTIONS, or "" if it has none.
+func htmlTagID(ids htmlIDs, section string, tag Line) string {
+	var prefix string
+	switch section {
+	case "OPTIONS":
+		prefix = "option-"
+	case "COMMANDS":
+		prefix = "command-"
+	default:
+		return ""
+	}
+	words := strings.Fields(strings.ReplaceAll(tag.String(), ",", " "))
+	if len(words) == 0 {
+		return ""
+	}
+	// Prefer the long name of options.
+	word := words[0]
+	for _, w := range words {
+		if strings.HasPrefix(w, "--") {
+			word = w
+			break
+		}
+	}
+	return ids.id(prefix + strings.TrimLeft(word, "-"))
+}
+
+// writeHTML writes in w the man page p, parsed in d, as a standalone HTML
+// page.
+func writeHTML(w io.Writer, p *Page, d *Document) {
+	section := p.Section
+	if section == "" {
+		section = "1"
+	}
+	description := p.Description
+	if description == "" {
+		description = "manual page for " + p.Name
+	}
+	title := strings.ToUpper(p.Name) + "(" + section + ")"
+	var date string
+	if len(d.Title) > 2 {
+		date = d.Title[2]
+	}
+
+	mfprintln(w, "<!DOCTYPE html>")
+	mfprintln(w, `<html lang="en">`)
+	mfprintln(w, "<head>")
+	mfprintln(w, `<meta charset="utf-8">`)
+	mfprintln(w, `<meta name="viewport" content="width=device-width, initial-scale=1">`)
+	mfprintf(w, "<meta name=\"generator\" content=\"gohelp2man %s\">\n", html.EscapeString(Version()))
+	mfprintf(w, "<title>%s(%s) — %s</title>\n", html.EscapeString(p.Name), section, html.EscapeString(description))
+	mfprintf(w, "<style>\n%s</style>\n", htmlStyle)
+	mfprintln(w, "</head>")
+	mfprintln(w, "<body>")
+	mfprintf(w, "<header><span>%s</span><span>%s</span><span>%s</span></header>\n",
+		html.EscapeString(title), html.EscapeString(p.Manual), html.EscapeString(title))
+	mfprintln(w, "<main>")
+	ids := make(htmlIDs)
+	for _, s := range d.Sections {
+		if s.Title == "" {
+			continue
+		}
+		id := ids.id(s.Title)
+		mfprintf(w, "<section id=\"%s\">\n", id)
+		mfprintf(w, "<h2><a href=\"#%s\">%s</a></h2>\n", id, html.EscapeString(s.Title))
+		list := ""
+		indent := 0
+		closeList := func() {
+			if list != "" {
+				mfprintf(w, "</%s>\n", list)
+				list = ""
+			}
+		}
+		for _, b := range s.Blocks {
+			kind := ""
+			if b.Kind == BlockTagged {
+				kind = htmlList(b)
+			}
+			if kind != list || b.Indent != indent {
+				closeList()
+			}
+			for ; indent < b.Indent; indent++ {
+				mfprintln(w, `<div class="indent">`)
+			}
+			for ; indent > b.Indent; indent-- {
+				mfprintln(w, "</div>")
+			}
+			if kind != "" && list == "" {
+				mfprintf(w, "<%s>\n", kind)
+				list = kind
+			}
+			switch b.Kind {
+			case BlockHeading:
+				id := ids.id(b.Tag.String())
+				mfprintf(w, "<h3 id=\"%s\"><a href=\"#%s\">%s</a></h3>\n", id, id, htmlInline(b.Tag))
+			case BlockTagged:
+				switch kind {
+				case "ul":
+					mfprintf(w, "<li>%s</li>\n", htmlLines(b.Lines))
+				case "ol":
+					mfprintf(w, "<li value=\"%s\">%s</li>\n", strings.TrimSuffix(b.Tag.String(), "."), htmlLines(b.Lines))
+				default:
+					if id := htmlTagID(ids, s.Title, b.Tag); id != "" {
+						mfprintf(w, "<dt id=\"%s\"><a class=\"anchor\" href=\"#%s\">%s</a></dt>\n", id, id, htmlInline(b.Tag))
+					} else {
+						mfprintf(w, "<dt>%s</dt>\n", htmlInline(b.Tag))
+					}
+					mfprintf(w, "<dd>%s</dd>\n", htmlLines(b.Lines))
+				}
+			case BlockPreformatted:
+				mfprint(w, "<pre>")
+				for _, l := range b.Lines {
+					mfprintln(w, htmlInline(l))
+				}
+				mfprintln(w, "</pre>")
+			default:
+				mfprintf(w, "<p>%s</p>\n", htmlLines(b.Lines))
+			}
+		}
+		closeList()
+		for ; indent > 0; indent-- {
+			mfprintln(w, "</div>")
+		}
+		mfprintln(w, "</section>")
+	}
+	mfprintln(w, "</main>")
+	mfprintf(w, "<footer><span>%s</span><span>%s</span><span>%s</span></footer>\n",
+		html.EscapeString(p.footer()), html.EscapeString(date), html.EscapeString(title))
+	mfprintln(w, "</body>")
+	mfprintln(w, "</html>")
+}
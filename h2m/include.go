@@ -0,0 +1,260 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Pattern is a block of an include file that must be inserted after the
+// first paragraph of the help output that matches Regexp.
+type Pattern struct {
+	Regexp *regexp.Regexp
+	Text   string
+
+	matched bool
+}
+
+func (p *Pattern) String() string {
+	return fmt.Sprintf("{/%s/ %q}", p.Regexp, p.Text)
+}
+
+// Option is a command line option set in an include file.
+type Option struct {
+	Name  string
+	Value string
+	Line  int
+}
+
+func (o *Option) String() string {
+	return fmt.Sprintf("{-%s %q %d}", o.Name, o.Value, o.Line)
+}
+
+type Include struct {
+	Sections      map[string]*Section
+	OtherSections []*Section
+	Patterns      []*Pattern
+	Options       []*Option
+	// Warnings are the likely mistakes of the include file found by
+	// ParseInclude.
+	Warnings []*Warning
+}
+
+// warn adds a warning about the given line.
+func (i *Include) warn(line int, format string, a ...any) {
+	i.Warnings = append(i.Warnings, &Warning{Line: line, Message: fmt.Sprintf(format, a...)})
+}
+
+// UnmatchedPatterns returns the patterns that have not been inserted in the
+// man page, because they did not match any paragraph.
+func (i *Include) UnmatchedPatterns() (unmatched []*Pattern) {
+	for _, p := range i.Patterns {
+		if !p.matched {
+			unmatched = append(unmatched, p)
+		}
+	}
+	return
+}
+
+// ReadInclude reads and parses the include file at path. If optional is
+// true, a missing file results in an empty Include.
+func ReadInclude(path string, optional bool) (include *Include, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if optional {
+			return &Include{}, nil
+		}
+		return nil, err
+	}
+	include, err = ParseInclude(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+	return
+}
+
+// parseOption parses an option line of an include file. Both "-name value"
+// and "--name=value" forms are accepted.
+func parseOption(line string, n int) *Option {
+	line = strings.TrimLeft(strings.TrimSpace(line), "-")
+	i := strings.IndexAny(line, "= \t")
+	if i == -1 {
+		return &Option{Name: line, Line: n}
+	}
+	return &Option{
+		Name:  line[:i],
+		Value: strings.TrimSpace(line[i+1:]),
+		Line:  n,
+	}
+}
+
+// addSection adds a new section to i from the given header, which can start
+// with a placement character, and returns it.
+func (i *Include) addSection(header string) *Section {
+	s := &Section{}
+	title := header
+	switch r := header[0]; r {
+	case '<', '=', '>':
+		s.Pos = r
+		title = header[1:]
+	}
+	title, found := findKnownSection(title)
+	s.Title = title
+	if found {
+		i.Sections[title] = s
+	} else {
+		i.OtherSections = append(i.OtherSections, s)
+	}
+	return s
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := range ra {
+		cur[0] = i + 1
+		for j := range rb {
+			cost := 1
+			if ra[i] == rb[j] {
+				cost = 0
+			}
+			cur[j+1] = prev[j] + cost
+			if d := prev[j+1] + 1; d < cur[j+1] {
+				cur[j+1] = d
+			}
+			if d := cur[j] + 1; d < cur[j+1] {
+				cur[j+1] = d
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// closeKnownSection returns the known section whose title is close enough to
+// the given one to be a misspelling of it, or an empty string.
+func closeKnownSection(title string) string {
+	for _, known := range KnownSections {
+		if editDistance(title, known) <= len(title)/4 {
+			return known
+		}
+	}
+	return ""
+}
+
+// AddSection adds a section with the given text to i, as if it was written
+// in the include file under the given header, e.g. "<DESCRIPTION". It is
+// ignored if i already has a section with the same title, and reports whether
+// the section has been added.
+func (i *Include) AddSection(header, text string) bool {
+	title := strings.TrimLeft(header, "<=>")
+	if title == "" {
+		return false
+	}
+	title, _ = findKnownSection(title)
+	if _, found := i.Sections[title]; found {
+		return false
+	}
+	for _, s := range i.OtherSections {
+		if s.Title == title {
+			return false
+		}
+	}
+	if i.Sections == nil {
+		i.Sections = make(map[string]*Section)
+	}
+	i.addSection(header).Text = text
+	return true
+}
+
+// ParseInclude parses an .h2m include file. Lines starting with '-' before
+// the first block are parsed as options. The duplicate sections, and the
+// unknown ones that are placed or look like misspelled known sections, are
+// reported in Warnings.
+func ParseInclude(r io.Reader) (*Include, error) {
+	i := &Include{Sections: make(map[string]*Section)}
+	headers := make(map[string]int)
+
+	var target *string
+	var text strings.Builder
+	finaliseBlock := func() {
+		if target != nil {
+			*target = strings.TrimSpace(text.String())
+		}
+		text.Reset()
+	}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if m := regexPattern.FindStringSubmatch(line); m != nil {
+			finaliseBlock()
+			expr := m[1]
+			if m[2] != "" {
+				expr = "(?" + m[2] + ")" + expr
+			}
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid pattern: %w", n, err)
+			}
+			p := &Pattern{Regexp: re}
+			i.Patterns = append(i.Patterns, p)
+			target = &p.Text
+			continue
+		}
+		m := regexSection.FindStringSubmatch(line)
+		if m != nil {
+			finaliseBlock()
+			s := i.addSection(m[1])
+			target = &s.Text
+			if prev, found := headers[s.Title]; found {
+				i.warn(n, "duplicate section [%s], already at line %d", s.Title, prev)
+			}
+			headers[s.Title] = n
+			if i.Sections[s.Title] == s {
+				continue
+			}
+			if known := closeKnownSection(s.Title); known != "" {
+				i.warn(n, "unknown section [%s], did you mean [%s]?", s.Title, known)
+			} else if s.Pos != 0 {
+				i.warn(n, "unknown section [%s], its placement %q is ignored", s.Title, s.Pos)
+			}
+			continue
+		}
+		if target == nil {
+			if strings.HasPrefix(line, "-") {
+				i.Options = append(i.Options, parseOption(line, n))
+			}
+			continue
+		}
+		text.WriteString(line)
+		text.WriteString("\n")
+	}
+	finaliseBlock()
+	return i, scanner.Err()
+}
@@ -0,0 +1,208 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestParseInclude(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected *Include
+	}{
+		{
+			"empty",
+			"",
+			&Include{Sections: map[string]*Section{}},
+		},
+		{
+			"single known section",
+			`[NAME]
+gohelp2man - generate a simple manual page for Go programs
+`,
+			&Include{Sections: map[string]*Section{
+				"NAME": {
+					Title: "NAME",
+					Text:  "gohelp2man - generate a simple manual page for Go programs",
+				},
+			}},
+		},
+		{
+			"lowercase known section",
+			`[name]
+gohelp2man - generate a simple manual page for Go programs
+`,
+			&Include{Sections: map[string]*Section{
+				"NAME": {
+					Title: "NAME",
+					Text:  "gohelp2man - generate a simple manual page for Go programs",
+				},
+			}},
+		},
+		{
+			"single other section",
+			`[Other section]
+This is a section that is not known.
+`,
+			&Include{Sections: map[string]*Section{}, OtherSections: []*Section{
+				{
+					Title: "OTHER SECTION",
+					Text:  "This is a section that is not known.",
+				},
+			}},
+		},
+		{
+			"positioned known section",
+			"[>DESCRIPTION]\nAppend\n",
+			&Include{Sections: map[string]*Section{
+				"DESCRIPTION": {"DESCRIPTION", "Append", '>'},
+			}},
+		},
+		{
+			"patterns",
+			`/^-verbose/
+Inserted after verbose.
+/case/i
+Inserted after Case.
+[AUTHOR]
+Nicolas Peugnet
+`,
+			&Include{
+				Sections: map[string]*Section{
+					"AUTHOR": {"AUTHOR", "Nicolas Peugnet", 0},
+				},
+				Patterns: []*Pattern{
+					{Regexp: regexp.MustCompile(`^-verbose`), Text: "Inserted after verbose."},
+					{Regexp: regexp.MustCompile(`(?i)case`), Text: "Inserted after Case."},
+				},
+			},
+		},
+		{
+			"options",
+			`Comment line
+-section 8
+--manual=System Administration Utilities
+-name   test command
+  -ignored because indented
+-version-string
+[NAME]
+-not an option
+`,
+			&Include{
+				Sections: map[string]*Section{
+					"NAME": {"NAME", "-not an option", 0},
+				},
+				Options: []*Option{
+					{"section", "8", 2},
+					{"manual", "System Administration Utilities", 3},
+					{"name", "test command", 4},
+					{"version-string", "", 6},
+				},
+			},
+		},
+		{
+			"warnings",
+			`[AUTHOR]
+First
+[Examples]
+Ex
+[>History]
+Before
+[author]
+Second
+[Example]
+Ex
+[EXAMPELS]
+Ex
+[Bugs]
+None
+`,
+			&Include{
+				Sections: map[string]*Section{
+					"AUTHOR":   {"AUTHOR", "Second", 0},
+					"EXAMPLES": {"EXAMPLES", "Ex", 0},
+				},
+				OtherSections: []*Section{
+					{"HISTORY", "Before", '>'},
+					{"EXAMPLE", "Ex", 0},
+					{"EXAMPELS", "Ex", 0},
+					{"BUGS", "None", 0},
+				},
+				Warnings: []*Warning{
+					{5, `unknown section [HISTORY], its placement '>' is ignored`},
+					{7, "duplicate section [AUTHOR], already at line 1"},
+					{9, "unknown section [EXAMPLE], did you mean [EXAMPLES]?"},
+					{11, "unknown section [EXAMPELS], did you mean [EXAMPLES]?"},
+				},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := ParseInclude(strings.NewReader(c.input))
+			if err != nil {
+				t.Error(err)
+			}
+			if !reflect.DeepEqual(c.expected, actual) {
+				t.Fatalf("expected %v, got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseIncludeInvalidPattern(t *testing.T) {
+	_, err := ParseInclude(strings.NewReader("[NAME]\ntest - test\n/(/\ntext\n"))
+	expected := "line 3: invalid pattern"
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Fatalf("expected error to contain %q, got %v", expected, err)
+	}
+}
+
+func TestIncludeAddSection(t *testing.T) {
+	i, err := ParseInclude(strings.NewReader("[DESCRIPTION]\nincluded\n[EXTRA]\nextra\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		header string
+		added  bool
+	}{
+		{">description", false},
+		{"extra", false},
+		{">environment", true},
+		{"Overview", true},
+		{"<", false},
+	}
+	for _, c := range cases {
+		if added := i.AddSection(c.header, "text"); added != c.added {
+			t.Errorf("%q: expected added: %v, got %v", c.header, c.added, added)
+		}
+	}
+	expected := &Section{"ENVIRONMENT", "text", '>'}
+	if !reflect.DeepEqual(expected, i.Sections["ENVIRONMENT"]) {
+		t.Errorf("expected %v, got %v", expected, i.Sections["ENVIRONMENT"])
+	}
+	if n := len(i.OtherSections); n != 2 || i.OtherSections[1].Title != "OVERVIEW" {
+		t.Errorf("expected OVERVIEW to be added to other sections, got %v", i.OtherSections)
+	}
+}
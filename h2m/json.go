@@ -0,0 +1,215 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// JSONVersion is the version of the schema of [FormatJSON]. It is incremented
+// for each incompatible change, adding fields is not one of them.
+const JSONVersion = 1
+
+// jsonDocument is the root object of [FormatJSON].
+type jsonDocument struct {
+	// Version is [JSONVersion].
+	Version int          `json:"version"`
+	Page    jsonPage     `json:"page"`
+	Help    *jsonHelp    `json:"help"`
+	Include *jsonInclude `json:"include"`
+}
+
+// jsonPage is the JSON object of a [Page].
+type jsonPage struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+	Section     string `json:"section"`
+	Manual      string `json:"manual"`
+}
+
+// jsonHelp is the JSON object of a [Help].
+type jsonHelp struct {
+	// Usage are the usage lines as printed in the help output, usually
+	// starting with the program name.
+	Usage    []string       `json:"usage"`
+	Flags    []*jsonFlag    `json:"flags"`
+	Commands []*jsonCommand `json:"commands"`
+	// Sections are the sections detected in the help output, in the
+	// order of the man page.
+	Sections   []*jsonSection `json:"sections"`
+	References []string       `json:"references"`
+}
+
+// jsonFlag is the JSON object of a [Flag].
+type jsonFlag struct {
+	// Names are the name and the aliases of the flag, with their leading
+	// dashes.
+	Names []string `json:"names"`
+	// Arg starts with its separator when it is attached to the names,
+	// e.g. "=N" or "[=WHEN]".
+	Arg     string `json:"arg"`
+	Type    string `json:"type"`
+	Bool    bool   `json:"bool"`
+	Default string `json:"default"`
+	Usage   string `json:"usage"`
+}
+
+// jsonCommand is the JSON object of a [Command].
+type jsonCommand struct {
+	// Names are the name and the aliases of the command.
+	Names []string `json:"names"`
+	Usage string   `json:"usage"`
+	// Help is null if the help output of the command was not retrieved.
+	Help *jsonHelp `json:"help"`
+}
+
+// jsonSection is the JSON object of a [Section].
+type jsonSection struct {
+	Title string `json:"title"`
+	// Position is "before", "replace" or "after" if the section of an
+	// include file is placed explicitly, else it is empty.
+	Position string `json:"position"`
+	Text     string `json:"text"`
+}
+
+// jsonPattern is the JSON object of a [Pattern].
+type jsonPattern struct {
+	Regexp  string `json:"regexp"`
+	Text    string `json:"text"`
+	Matched bool   `json:"matched"`
+}
+
+// jsonOption is the JSON object of an [Option].
+type jsonOption struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Line  int    `json:"line"`
+}
+
+// jsonInclude is the JSON object of an [Include].
+type jsonInclude struct {
+	// Sections are the known sections, in the order of the man page,
+	// followed by the other ones in the order of the include file.
+	Sections []*jsonSection `json:"sections"`
+	Patterns []*jsonPattern `json:"patterns"`
+	Options  []*jsonOption  `json:"options"`
+}
+
+// jsonPositions are the positions of the sections by placement character.
+var jsonPositions = map[byte]string{
+	'<': "before",
+	'=': "replace",
+	'>': "after",
+}
+
+// newJSONSection returns the JSON object of s.
+func newJSONSection(s *Section) *jsonSection {
+	return &jsonSection{Title: s.Title, Position: jsonPositions[s.Pos], Text: s.Text}
+}
+
+// newJSONSections returns the JSON objects of the known sections, in the
+// order of the man page, followed by the ones of others.
+func newJSONSections(known map[string]*Section, others []*Section) []*jsonSection {
+	list := make([]*jsonSection, 0, len(known)+len(others))
+	for _, title := range KnownSections {
+		if s, found := known[title]; found {
+			list = append(list, newJSONSection(s))
+		}
+	}
+	for _, s := range others {
+		list = append(list, newJSONSection(s))
+	}
+	return list
+}
+
+// newJSONHelp returns the JSON object of h, or nil if h is nil.
+func newJSONHelp(h *Help) *jsonHelp {
+	if h == nil {
+		return nil
+	}
+	j := &jsonHelp{
+		Usage:      []string{},
+		Flags:      make([]*jsonFlag, len(h.Flags)),
+		Commands:   make([]*jsonCommand, len(h.Commands)),
+		Sections:   newJSONSections(h.Sections, nil),
+		References: append([]string{}, h.References...),
+	}
+	if h.Usage != "" {
+		j.Usage = strings.Split(h.Usage, "\n")
+	}
+	for i, f := range h.Flags {
+		j.Flags[i] = &jsonFlag{
+			Names:   f.names(),
+			Arg:     f.Arg,
+			Type:    f.Type,
+			Bool:    f.IsBool,
+			Default: f.Default,
+			Usage:   f.Usage,
+		}
+	}
+	for i, c := range h.Commands {
+		j.Commands[i] = &jsonCommand{
+			Names: append([]string{c.Name}, c.Aliases...),
+			Usage: c.Usage,
+			Help:  newJSONHelp(c.Help),
+		}
+	}
+	return j
+}
+
+// newJSONInclude returns the JSON object of i, or nil if i is nil.
+func newJSONInclude(i *Include) *jsonInclude {
+	if i == nil {
+		return nil
+	}
+	j := &jsonInclude{
+		Sections: newJSONSections(i.Sections, i.OtherSections),
+		Patterns: make([]*jsonPattern, len(i.Patterns)),
+		Options:  make([]*jsonOption, len(i.Options)),
+	}
+	for k, p := range i.Patterns {
+		j.Patterns[k] = &jsonPattern{Regexp: p.Regexp.String(), Text: p.Text, Matched: p.matched}
+	}
+	for k, o := range i.Options {
+		j.Options[k] = &jsonOption{Name: o.Name, Value: o.Value, Line: o.Line}
+	}
+	return j
+}
+
+// writeJSON writes in w the man page p made of the given include and help,
+// as the JSON object described by [FormatJSON].
+func writeJSON(w io.Writer, p *Page, include *Include, help *Help) error {
+	e := json.NewEncoder(w)
+	e.SetEscapeHTML(false)
+	e.SetIndent("", "\t")
+	return e.Encode(&jsonDocument{
+		Version: JSONVersion,
+		Page: jsonPage{
+			Name:        p.Name,
+			Description: p.Description,
+			Version:     p.Version,
+			Section:     p.Section,
+			Manual:      p.Manual,
+		},
+		Help:    newJSONHelp(help),
+		Include: newJSONInclude(include),
+	})
+}
@@ -0,0 +1,143 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"io"
+	"regexp"
+	"strings"
+)
+
+var markdownEscaper = NewRegexpReplacer(
+	`<(https?://[^\s>]+)>`, `<$1>`,
+	`[\\`+"`"+`*_\[\]<>]`, `\$0`,
+)
+
+// regexMarkdownLineStart matches the start of lines that would be interpreted
+// as block markup by CommonMark.
+var regexMarkdownLineStart = regexp.MustCompile(`^(?:[#>=|+-]|\d+[.)](?:\s|$))`)
+
+var regexOrderedTag = regexp.MustCompile(`^\d+\.$`)
+
+// markdownInline returns the given line as CommonMark inline content.
+func markdownInline(line Line) string {
+	var b strings.Builder
+	// Merge the consecutive spans of the same font.
+	var merged Line
+	for _, s := range line {
+		if s.Break {
+			continue
+		}
+		n := len(merged)
+		if n != 0 && merged[n-1].Font == s.Font {
+			merged[n-1].Text += s.Text
+		} else {
+			merged = append(merged, s)
+		}
+	}
+	for _, s := range merged {
+		text := markdownEscaper.Replace(s.Text)
+		var mark string
+		switch s.Font {
+		case FontBold:
+			mark = "**"
+		case FontItalic:
+			mark = "*"
+		}
+		trimmed := strings.TrimSpace(text)
+		if mark == "" || trimmed == "" {
+			b.WriteString(text)
+			continue
+		}
+		// Emphasis cannot start or end with a space.
+		b.WriteString(text[:strings.Index(text, trimmed)])
+		b.WriteString(mark + trimmed + mark)
+		b.WriteString(text[strings.Index(text, trimmed)+len(trimmed):])
+	}
+	s := strings.TrimSpace(b.String())
+	if regexMarkdownLineStart.MatchString(s) {
+		s = `\` + s
+	}
+	return s
+}
+
+// markdownLines returns the given lines as CommonMark inline content,
+// separated by hard line breaks and indented by prefix.
+func markdownLines(lines []Line, prefix string) string {
+	texts := make([]string, 0, len(lines))
+	for _, l := range lines {
+		if s := markdownInline(l); s != "" {
+			texts = append(texts, s)
+		}
+	}
+	return strings.Join(texts, "\\\n"+prefix)
+}
+
+// writeMarkdown writes in w the man page p, parsed in d, as CommonMark.
+func writeMarkdown(w io.Writer, p *Page, d *Document) {
+	section := p.Section
+	if section == "" {
+		section = "1"
+	}
+	mfprintf(w, "# %s(%s)\n", markdownEscaper.Replace(p.Name), section)
+	for _, s := range d.Sections {
+		if s.Title == "" {
+			continue
+		}
+		mfprintf(w, "\n## %s\n", s.Title)
+		if s.Title == "SYNOPSIS" {
+			mfprintln(w, "\n```")
+			for _, b := range s.Blocks {
+				for _, l := range b.Lines {
+					mfprintln(w, l.String())
+				}
+			}
+			mfprintln(w, "```")
+			continue
+		}
+		for _, b := range s.Blocks {
+			mfprintln(w)
+			switch b.Kind {
+			case BlockHeading:
+				mfprintf(w, "### %s\n", markdownInline(b.Tag))
+			case BlockTagged:
+				tag := b.Tag.String()
+				switch {
+				case tag == "•" || tag == "":
+					mfprintf(w, "- %s\n", markdownLines(b.Lines, "  "))
+				case regexOrderedTag.MatchString(tag):
+					mfprintf(w, "%s %s\n", tag, markdownLines(b.Lines, strings.Repeat(" ", len(tag)+1)))
+				default:
+					mfprintf(w, "- %s", markdownInline(b.Tag))
+					if body := markdownLines(b.Lines, "  "); body != "" {
+						mfprintf(w, "\\\n  %s", body)
+					}
+					mfprintln(w)
+				}
+			case BlockPreformatted:
+				mfprintln(w, "```")
+				for _, l := range b.Lines {
+					mfprintln(w, l.String())
+				}
+				mfprintln(w, "```")
+			default:
+				mfprintln(w, markdownLines(b.Lines, ""))
+			}
+		}
+	}
+}
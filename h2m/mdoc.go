@@ -0,0 +1,496 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// mdocMacros are the callable macros of mdoc(7), whose names must be escaped
+// when used as arguments.
+var mdocMacros = map[string]bool{
+	"Ac": true, "Ad": true, "An": true, "Ao": true, "Ap": true, "Aq": true,
+	"Ar": true, "At": true, "Bc": true, "Bo": true, "Bq": true, "Brc": true,
+	"Bro": true, "Brq": true, "Bsx": true, "Bx": true, "Cd": true, "Cm": true,
+	"Dc": true, "Do": true, "Dq": true, "Dv": true, "Dx": true, "Ec": true,
+	"Em": true, "En": true, "Eo": true, "Er": true, "Es": true, "Ev": true,
+	"Fa": true, "Fc": true, "Fl": true, "Fn": true, "Fo": true, "Fr": true,
+	"Ft": true, "Fx": true, "Ic": true, "Li": true, "Lk": true, "Ms": true,
+	"Mt": true, "Nm": true, "No": true, "Ns": true, "Nx": true, "Oc": true,
+	"Oo": true, "Op": true, "Ox": true, "Pa": true, "Pc": true, "Pf": true,
+	"Po": true, "Pq": true, "Qc": true, "Ql": true, "Qo": true, "Qq": true,
+	"Sc": true, "So": true, "Sq": true, "St": true, "Sx": true, "Sy": true,
+	"Ta": true, "Tn": true, "Ux": true, "Va": true, "Vt": true, "Xc": true,
+	"Xo": true, "Xr": true,
+}
+
+const (
+	mdocOpening = "(["
+	mdocClosing = ".,:;)]?!"
+)
+
+var (
+	regexMdocFlag     = regexp.MustCompile(`^--?\w[\w.-]*$`)
+	regexMdocSentence = regexp.MustCompile(`(\S[.!?]["')\]]*) +(\S)`)
+	regexMdocArgument = regexp.MustCompile(`^[^a-z]*[A-Z<][^a-z]*$`)
+)
+
+// mdocEscape escapes a text for mdoc(7), including the non-ASCII characters
+// as the input encoding is not always detected.
+func mdocEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\e`)
+		case r == '"':
+			b.WriteString(`\(dq`)
+		case r > unicode.MaxASCII:
+			fmt.Fprintf(&b, `\[u%04X]`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// mdocArg escapes a word to be used as the argument of a macro.
+func mdocArg(word string) string {
+	if mdocMacros[word] || len(word) == 1 && strings.Contains(mdocOpening+mdocClosing+"|", word) {
+		return `\&` + word
+	}
+	return mdocEscape(word)
+}
+
+// mdocArgs escapes the words of the given text to be used as the arguments of
+// a macro.
+func mdocArgs(text string) string {
+	words := strings.Fields(text)
+	for i, w := range words {
+		words[i] = mdocArg(w)
+	}
+	return strings.Join(words, " ")
+}
+
+// mdocDelimiters returns the given delimiters as separate arguments.
+func mdocDelimiters(delims string) string {
+	return strings.Join(strings.Split(delims, ""), " ")
+}
+
+// mdocFlag returns the macro of a flag, e.g. "Fl o" for "-o", followed by its
+// argument if it is attached, e.g. "--count=N" or "--color[=WHEN]".
+func mdocFlag(flag string) string {
+	flag = strings.TrimPrefix(flag, "-")
+	if name, value, found := strings.Cut(flag, "[="); found && strings.HasSuffix(value, "]") {
+		return "Fl " + mdocArg(name) + " Ns Op = Ns Ar " + mdocArg(strings.TrimSuffix(value, "]"))
+	}
+	if name, value, found := strings.Cut(flag, "="); found {
+		return "Fl " + mdocArg(name) + " Ns = Ns Ar " + mdocArg(value)
+	}
+	return "Fl " + mdocArg(flag)
+}
+
+// mdocText returns the given text as text lines, one per sentence.
+func mdocText(text string) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(regexMdocSentence.ReplaceAllString(mdocEscape(text), "$1\n$2"), "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, ".") || strings.HasPrefix(l, "'") {
+			lines[i] = `\&` + l
+		}
+	}
+	return lines
+}
+
+// mdocInline returns the macro of a span in bold or italic, and the length of
+// the beginning of the next span it consumed.
+func mdocInline(s Span, next *Span) (string, int) {
+	words := strings.Fields(s.Text)
+	if s.Font == FontBold {
+		if next != nil && next.Font == FontRoman && len(words) == 1 && regexHTMLName.MatchString(words[0]) {
+			if m := regexHTMLManRef.FindStringSubmatch(next.Text); m != nil {
+				return "Xr " + mdocArg(words[0]) + " " + m[1], len(m[0])
+			}
+		}
+		if len(words) == 1 && regexMdocFlag.MatchString(words[0]) {
+			return mdocFlag(words[0]), 0
+		}
+		return "Sy " + mdocArgs(s.Text), 0
+	}
+	if len(words) == 1 {
+		return "Ar " + mdocArg(words[0]), 0
+	}
+	return "Em " + mdocArgs(s.Text), 0
+}
+
+// mdocLines returns the given line as mdoc(7) text and macro lines.
+func mdocLines(line Line) (out []string) {
+	var spans Line
+	for _, s := range line {
+		n := len(spans)
+		if n != 0 && !s.Break && !spans[n-1].Break && spans[n-1].Font == s.Font {
+			spans[n-1].Text += s.Text
+		} else {
+			spans = append(spans, s)
+		}
+	}
+	var text string
+	flush := func() {
+		out = append(out, mdocText(text)...)
+		text = ""
+	}
+	// The macro line continued by the next span, when they are attached.
+	var pending string
+	for i := 0; i < len(spans); i++ {
+		s := spans[i]
+		switch {
+		case s.Break:
+			flush()
+			out = append(out, ".br")
+			continue
+		case s.Font == FontRoman || strings.TrimSpace(s.Text) == "":
+			text += s.Text
+			continue
+		}
+		// The word attached before the macro.
+		var prefix string
+		if text != "" && !strings.HasSuffix(text, " ") {
+			start := strings.LastIndex(text, " ") + 1
+			text, prefix = text[:start], text[start:]
+		}
+		flush()
+		var next *Span
+		if i+1 < len(spans) && !spans[i+1].Break {
+			next = &spans[i+1]
+		}
+		macro, consumed := mdocInline(s, next)
+		l := "." + macro
+		if pending != "" {
+			l, pending = pending+" Ns "+macro, ""
+		} else if prefix != "" {
+			if strings.Trim(prefix, mdocOpening) == "" {
+				l = "." + strings.Replace(macro, " ", " "+mdocDelimiters(prefix)+" ", 1)
+			} else {
+				l = ".Pf " + mdocArg(prefix) + " " + macro
+			}
+		}
+		if next != nil && next.Font != FontRoman && !strings.HasSuffix(s.Text, " ") && !strings.HasPrefix(next.Text, " ") {
+			pending = l
+			continue
+		}
+		if next != nil && next.Font == FontRoman {
+			rest := next.Text[consumed:]
+			// The word attached after the macro.
+			end := strings.IndexAny(rest, " \t")
+			if end == -1 {
+				end = len(rest)
+			}
+			word := rest[:end]
+			delims := word[:len(word)-len(strings.TrimLeft(word, mdocClosing))]
+			if delims != "" {
+				l += " " + mdocDelimiters(delims)
+			}
+			if word = word[len(delims):]; word != "" {
+				l += " Ns No " + mdocArg(word)
+			}
+			next.Text = rest[end:]
+		}
+		out = append(out, l)
+	}
+	flush()
+	return
+}
+
+// mdocSynopsisToken returns the macro arguments of a word of a synopsis.
+func mdocSynopsisToken(word string, optional bool) string {
+	switch {
+	case word == "|":
+		return word
+	case regexMdocFlag.MatchString(word) || strings.HasPrefix(word, "--") && strings.Contains(word, "="):
+		return mdocFlag(word)
+	case optional || regexMdocArgument.MatchString(word):
+		return "Ar " + mdocArg(word)
+	default:
+		return "Cm " + mdocArg(word)
+	}
+}
+
+// mdocSynopsis returns a synopsis line as mdoc(7) macro lines. The name of the
+// program is written with .Nm, the flags with .Fl, the arguments with .Ar and
+// the optional elements in brackets with .Op.
+func mdocSynopsis(name, synopsis string) (out []string) {
+	var words []string
+	for _, w := range strings.Fields(synopsis) {
+		for w != "" {
+			if i := strings.IndexAny(w, "[]"); i == -1 {
+				words = append(words, w)
+				w = ""
+			} else {
+				if i != 0 {
+					words = append(words, w[:i])
+				}
+				words, w = append(words, w[i:i+1]), w[i+1:]
+			}
+		}
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	if words[0] == name {
+		out = append(out, ".Nm")
+	} else {
+		out = append(out, ".Nm "+mdocArg(words[0]))
+	}
+	depth := 0
+	var l string
+	for _, w := range words[1:] {
+		switch {
+		case w == "[":
+			if depth == 0 {
+				l = ".Op"
+			} else {
+				l += " Oo"
+			}
+			depth++
+		case w == "]" && depth > 0:
+			depth--
+			if depth == 0 {
+				out = append(out, l)
+			} else {
+				l += " Oc"
+			}
+		case w == "...":
+			if depth != 0 {
+				l += " ..."
+			} else if n := len(out) - 1; n > 0 {
+				out[n] += " ..."
+			} else {
+				out = append(out, ".Ar ...")
+			}
+		case depth == 0:
+			out = append(out, "."+mdocSynopsisToken(w, false))
+		default:
+			l += " " + mdocSynopsisToken(w, true)
+		}
+	}
+	for ; depth > 0; depth-- {
+		if depth == 1 {
+			out = append(out, l)
+		} else {
+			l += " Oc"
+		}
+	}
+	return
+}
+
+// mdocTag returns the tag of a tagged block as the arguments of an .It macro.
+func mdocTag(section string, tag Line) string {
+	// The words of the tag with the font of their beginning, as a flag and
+	// its attached argument are written in different fonts, e.g. "--count=N".
+	var words []Span
+	attached := false
+	for _, s := range tag {
+		fields := strings.Fields(s.Text)
+		for i, w := range fields {
+			if i == 0 && attached && strings.HasPrefix(s.Text, w) {
+				words[len(words)-1].Text += w
+			} else {
+				words = append(words, Span{Text: w, Font: s.Font})
+			}
+		}
+		if s.Text != "" {
+			attached = len(fields) != 0 && strings.HasSuffix(s.Text, fields[len(fields)-1])
+		}
+	}
+	var args []string
+	for _, s := range words {
+		w := s.Text
+		if w == "," {
+			args = append(args, w)
+			continue
+		}
+		comma := strings.HasSuffix(w, ",")
+		w = strings.TrimSuffix(w, ",")
+		switch {
+		case regexMdocFlag.MatchString(w) || strings.HasPrefix(w, "-") && strings.Contains(w, "="):
+			args = append(args, mdocFlag(w))
+		case s.Font == FontBold && section == "COMMANDS":
+			args = append(args, "Cm "+mdocArg(w))
+		case s.Font == FontBold:
+			args = append(args, "Sy "+mdocArg(w))
+		case s.Font == FontItalic || section == "OPTIONS":
+			args = append(args, "Ar "+mdocArg(w))
+		default:
+			args = append(args, "No "+mdocArg(w))
+		}
+		if comma {
+			args = append(args, ",")
+		}
+	}
+	return strings.Join(args, " ")
+}
+
+// mdocList returns the type of list of a tagged block.
+func mdocList(b *Block) string {
+	switch kind := htmlList(b); kind {
+	case "ul":
+		return "-bullet"
+	case "ol":
+		return "-enum"
+	default:
+		return "-tag -width Ds"
+	}
+}
+
+// writeMdoc writes in w the man page p, parsed in d, as mdoc(7).
+func writeMdoc(w io.Writer, p *Page, d *Document) {
+	section := p.Section
+	if section == "" {
+		section = "1"
+	}
+	date := now().Format("January 2, 2006")
+	if len(d.Title) > 2 {
+		if t, err := time.Parse("2006-01-02", d.Title[2]); err == nil {
+			date = t.Format("January 2, 2006")
+		}
+	}
+	mfprintf(w, ".\\\" Generated by gohelp2man %s; DO NOT EDIT.\n", Version())
+	mfprintf(w, ".Dd %s\n", date)
+	mfprintf(w, ".Dt %s %s\n", mdocArg(strings.ToUpper(p.Name)), section)
+	mfprintln(w, ".Os")
+	for _, s := range d.Sections {
+		if s.Title == "" {
+			continue
+		}
+		mfprintf(w, ".Sh %s\n", mdocArgs(s.Title))
+		switch s.Title {
+		case "NAME":
+			description := p.Description
+			if len(s.Blocks) != 0 && len(s.Blocks[0].Lines) != 0 {
+				if _, d, found := strings.Cut(s.Blocks[0].Lines[0].String(), " - "); found {
+					description = d
+				}
+			}
+			if description == "" {
+				description = "manual page for " + p.Name
+			}
+			mfprintf(w, ".Nm %s\n.Nd %s\n", mdocArg(p.Name), mdocEscape(strings.TrimSpace(description)))
+			continue
+		case "SYNOPSIS":
+			for _, b := range s.Blocks {
+				for _, l := range b.Lines {
+					for _, m := range mdocSynopsis(p.Name, l.String()) {
+						mfprintln(w, m)
+					}
+				}
+			}
+			continue
+		}
+		writeMdocBlocks(w, s)
+	}
+}
+
+// writeMdocBlocks writes in w the blocks of the section s as mdoc(7). The
+// tagged blocks are written as lists, nested according to their indentation.
+func writeMdocBlocks(w io.Writer, s *DocSection) {
+	type list struct {
+		kind   string
+		indent int
+	}
+	var lists []list
+	closeLists := func(indent int) {
+		for len(lists) != 0 && lists[len(lists)-1].indent >= indent {
+			mfprintln(w, ".El")
+			lists = lists[:len(lists)-1]
+		}
+	}
+	// The previous element, to know whether a new paragraph is needed.
+	prev := ""
+	for _, b := range s.Blocks {
+		switch b.Kind {
+		case BlockHeading:
+			closeLists(0)
+			mfprintf(w, ".Ss %s\n", mdocArgs(b.Tag.String()))
+			prev = "heading"
+			continue
+		case BlockTagged:
+			kind := mdocList(b)
+			n := len(lists)
+			if n != 0 && (lists[n-1].indent > b.Indent || lists[n-1].indent == b.Indent && lists[n-1].kind != kind) {
+				closeLists(b.Indent)
+				n = len(lists)
+			}
+			if n == 0 || lists[n-1].indent < b.Indent {
+				mfprintf(w, ".Bl %s\n", kind)
+				lists = append(lists, list{kind, b.Indent})
+			}
+			if kind == "-tag -width Ds" {
+				mfprintf(w, ".It %s\n", mdocTag(s.Title, b.Tag))
+			} else {
+				mfprintln(w, ".It")
+			}
+			for _, l := range b.Lines {
+				for _, m := range mdocLines(l) {
+					mfprintln(w, m)
+				}
+			}
+			prev = "item"
+			continue
+		}
+		if n := len(lists); n != 0 && lists[n-1].indent >= b.Indent {
+			closeLists(b.Indent)
+			prev = "list"
+		}
+		if b.Kind == BlockPreformatted {
+			mfprintln(w, ".Bd -literal -offset indent")
+			for _, l := range b.Lines {
+				line := mdocEscape(l.String())
+				if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+					line = `\&` + line
+				}
+				mfprintln(w, line)
+			}
+			mfprintln(w, ".Ed")
+			prev = "display"
+			continue
+		}
+		var lines []string
+		for _, l := range b.Lines {
+			lines = append(lines, mdocLines(l)...)
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		if prev == "text" || prev == "list" || prev == "display" {
+			mfprintln(w, ".Pp")
+		}
+		for _, l := range lines {
+			mfprintln(w, l)
+		}
+		prev = "text"
+	}
+	closeLists(0)
+}
@@ -0,0 +1,97 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMdocSynopsis(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"basic", "test [OPTION]... [ARGUMENT]...", ".Nm\n.Op Ar OPTION ...\n.Op Ar ARGUMENT ..."},
+		{"flags", "test [-v] [-o FILE] --count=N", ".Nm\n.Op Fl v\n.Op Fl o Ar FILE\n.Fl -count Ns = Ns Ar N"},
+		{"nested", "test [-o [FILE]]", ".Nm\n.Op Fl o Oo Ar FILE Oc"},
+		{"command", "tool build PACKAGE...", ".Nm tool\n.Cm build\n.Ar PACKAGE..."},
+		{"alternatives", "test [-a | -b]", ".Nm\n.Op Fl a | Fl b"},
+		{"unclosed", "test [-v", ".Nm\n.Op Fl v"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual := strings.Join(mdocSynopsis("test", c.input), "\n")
+			if actual != c.expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMdocLines(t *testing.T) {
+	r := func(s string) Span { return Span{Text: s, Font: FontRoman} }
+	b := func(s string) Span { return Span{Text: s, Font: FontBold} }
+	i := func(s string) Span { return Span{Text: s, Font: FontItalic} }
+	cases := []struct {
+		name     string
+		input    Line
+		expected string
+	}{
+		{"sentences", Line{r("One. Two! .Three")}, "One.\nTwo!\n\\&.Three"},
+		{"flag", Line{r("Use "), b("-o"), r(" to write.")}, "Use\n.Fl o\nto write."},
+		{"reference", Line{r("See "), b("man"), r("(1), then.")}, "See\n.Xr man 1 ,\nthen."},
+		{"prefix", Line{r("a ["), i("FILE"), r("]...")}, "a\n.Ar [ FILE ] . . ."},
+		{"word prefix", Line{r("x"), b("Ar")}, ".Pf x Sy \\&Ar"},
+		{"attached", Line{b("["), i("section"), b("]")}, ".Sy \\&[ Ns Ar section Ns Sy \\&]"},
+		{"break", Line{r("a"), {Break: true}}, "a\n.br"},
+		{"escapes", Line{r(`"\ ©`)}, `\(dq\e \[u00A9]`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual := strings.Join(mdocLines(c.input), "\n")
+			if actual != c.expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMdocTag(t *testing.T) {
+	r := func(s string) Span { return Span{Text: s, Font: FontRoman} }
+	b := func(s string) Span { return Span{Text: s, Font: FontBold} }
+	cases := []struct {
+		name     string
+		section  string
+		input    Line
+		expected string
+	}{
+		{"aliases", "OPTIONS", Line{b("-o"), r(", "), b("--output"), r(" FILE")}, "Fl o , Fl -output Ar FILE"},
+		{"equal arg", "OPTIONS", Line{b("--count"), r("=N")}, "Fl -count Ns = Ns Ar N"},
+		{"optional arg", "OPTIONS", Line{b("--color"), r("[=WHEN]")}, "Fl -color Ns Op = Ns Ar WHEN"},
+		{"command", "COMMANDS", Line{b("build"), r(", "), b("b")}, "Cm build , Cm b"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := mdocTag(c.section, c.input); actual != c.expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", c.expected, actual)
+			}
+		})
+	}
+}
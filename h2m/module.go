@@ -0,0 +1,275 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/build"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ModuleInfo holds the metadata of the Go module of a package.
+type ModuleInfo struct {
+	// Path is the module path, as declared in go.mod.
+	Path string
+	// Package is the import path of the package.
+	Package string
+	// License is the SPDX identifier of the license of the module, empty
+	// if it could not be detected.
+	License string
+	// Copyright is the copyright notice found in the license file.
+	Copyright string
+	// Repository is the URL of the repository of the module, empty if
+	// unknown.
+	Repository string
+}
+
+// licenseFiles are the names of the files searched for the license of a
+// module, in order of preference.
+var licenseFiles = []string{"LICENSE", "LICENSE.md", "LICENSE.txt", "COPYING", "COPYING.md"}
+
+// licenses are the SPDX identifiers of the recognised licenses, with
+// the phrases that identify them. The first match wins. The identifiers of the
+// GNU licenses are completed by gnuVariant.
+var licenses = []struct {
+	id      string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"GNU AFFERO GENERAL PUBLIC LICENSE Version 3"}},
+	{"LGPL-2.1", []string{"GNU LESSER GENERAL PUBLIC LICENSE Version 2.1"}},
+	{"LGPL-3.0", []string{"GNU LESSER GENERAL PUBLIC LICENSE Version 3"}},
+	{"GPL-2.0", []string{"GNU GENERAL PUBLIC LICENSE Version 2"}},
+	{"GPL-3.0", []string{"GNU GENERAL PUBLIC LICENSE Version 3"}},
+	{"Apache-2.0", []string{"Apache License Version 2.0"}},
+	{"MPL-2.0", []string{"Mozilla Public License Version 2.0"}},
+	{"BSD-3-Clause", []string{"Redistribution and use in source and binary forms", "Neither the name"}},
+	{"BSD-2-Clause", []string{"Redistribution and use in source and binary forms"}},
+	{"MIT", []string{"Permission is hereby granted, free of charge"}},
+	{"ISC", []string{"Permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"Unlicense", []string{"This is free and unencumbered software released into the public domain"}},
+}
+
+var (
+	regexSPDX      = regexp.MustCompile(`SPDX-License-Identifier:\s*([-\w.+]+)`)
+	regexCopyright = regexp.MustCompile(`(?i)^\s*Copyright\s+(?:\(c\)|©)?\s*\d`)
+	regexSCPRemote = regexp.MustCompile(`^[\w.-]+@([\w.-]+):(.*)$`)
+)
+
+// detectLicense returns the SPDX identifier of the given license text.
+func detectLicense(text []byte) string {
+	if m := regexSPDX.FindSubmatch(text); m != nil {
+		return string(m[1])
+	}
+	normalized := strings.Join(strings.Fields(string(text)), " ")
+	for _, l := range licenses {
+		found := true
+		for _, p := range l.phrases {
+			found = found && strings.Contains(normalized, p)
+		}
+		if found && strings.Contains(l.id, "GPL-") {
+			return l.id + gnuVariant(normalized)
+		}
+		if found {
+			return l.id
+		}
+	}
+	return ""
+}
+
+// gnuVariant returns the suffix of the SPDX identifier of a GNU license from
+// its normalized text: "-or-later" if a notice allows to use any later
+// version, else "-only". The notice given as an example in the instructions
+// at the end of the license is ignored.
+func gnuVariant(normalized string) string {
+	terms, _, _ := strings.Cut(normalized, "How to Apply These Terms")
+	if strings.Contains(terms, "(at your option) any later version") {
+		return "-or-later"
+	}
+	return "-only"
+}
+
+// detectCopyright returns the first copyright notice of the given license
+// text, ignoring the ones of the license itself and of its instructions.
+func detectCopyright(text []byte) string {
+	s := bufio.NewScanner(bytes.NewReader(text))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if regexCopyright.MatchString(line) && !strings.Contains(line, "Free Software Foundation") &&
+			!strings.Contains(line, "name of author") {
+			return line
+		}
+	}
+	return ""
+}
+
+// repositoryURL returns the web URL of the given git remote URL, without its
+// credentials, e.g. the token of a CI job.
+func repositoryURL(remote string) string {
+	remote = strings.TrimSuffix(strings.TrimSpace(remote), ".git")
+	if m := regexSCPRemote.FindStringSubmatch(remote); m != nil {
+		return "https://" + m[1] + "/" + m[2]
+	}
+	u, err := url.Parse(remote)
+	if err != nil {
+		return ""
+	}
+	switch u.Scheme {
+	case "ssh", "git":
+		u.Host = u.Hostname()
+	case "http", "https":
+	default:
+		return ""
+	}
+	u.Scheme, u.User = "https", nil
+	return u.String()
+}
+
+// knownHosts are the forges whose repository URL can be deduced from the
+// first three elements of a module path.
+var knownHosts = []string{"github.com", "gitlab.com", "codeberg.org", "bitbucket.org", "git.sr.ht"}
+
+// findRepository returns the URL of the repository of the module at dir,
+// from its "origin" git remote, or else from its module path.
+func findRepository(dir, modPath string) string {
+	out, err := exec.Command("git", "-C", dir, "remote", "get-url", "origin").Output()
+	if err == nil {
+		if repo := repositoryURL(string(out)); repo != "" {
+			return repo
+		}
+	}
+	elems := strings.Split(modPath, "/")
+	for _, host := range knownHosts {
+		if elems[0] == host && len(elems) >= 3 {
+			return "https://" + strings.Join(elems[:3], "/")
+		}
+	}
+	return ""
+}
+
+// modulePath returns the module path declared in the given go.mod content.
+func modulePath(gomod []byte) string {
+	s := bufio.NewScanner(bytes.NewReader(gomod))
+	for s.Scan() {
+		line, _, _ := strings.Cut(s.Text(), "//")
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "module" {
+			if p, err := strconv.Unquote(fields[1]); err == nil {
+				return p
+			}
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// ReadModuleInfo returns the metadata of the module of the Go package found at
+// pkg, which can be a directory or an import path. The module is the first
+// parent directory with a go.mod file.
+func ReadModuleInfo(pkg string) (*ModuleInfo, error) {
+	p, err := importPackage(pkg, build.FindOnly)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := filepath.Abs(p.Dir)
+	if err != nil {
+		return nil, err
+	}
+	pkgDir := dir
+	for {
+		gomod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			return readModuleInfo(dir, pkgDir, gomod)
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, fmt.Errorf("%s: not in a Go module", pkgDir)
+		}
+		dir = parent
+	}
+}
+
+// readModuleInfo returns the metadata of the module at dir, whose go.mod
+// content is gomod, for its package at pkgDir.
+func readModuleInfo(dir, pkgDir string, gomod []byte) (*ModuleInfo, error) {
+	m := &ModuleInfo{Path: modulePath(gomod)}
+	if m.Path == "" {
+		return nil, fmt.Errorf("%s: missing module directive", filepath.Join(dir, "go.mod"))
+	}
+	rel, err := filepath.Rel(dir, pkgDir)
+	if err != nil {
+		return nil, err
+	}
+	m.Package = path.Join(m.Path, filepath.ToSlash(rel))
+	for _, name := range licenseFiles {
+		text, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil {
+			m.License = detectLicense(text)
+			m.Copyright = detectCopyright(text)
+			break
+		}
+	}
+	m.Repository = findRepository(dir, m.Path)
+	return m, nil
+}
+
+// Sections returns the REPORTING BUGS, COPYRIGHT and SEE ALSO sections that
+// can be deduced from the module metadata.
+func (m *ModuleInfo) Sections() map[string]*Section {
+	sections := make(map[string]*Section)
+	if m.Repository != "" {
+		tracker := m.Repository + "/issues"
+		if strings.HasPrefix(m.Repository, "https://gitlab.com/") {
+			tracker = m.Repository + "/-/issues"
+		}
+		sections["REPORTING BUGS"] = &Section{
+			Title: "REPORTING BUGS",
+			Text:  "Report bugs to <" + tracker + ">.",
+		}
+	}
+	var copyright []string
+	if m.Copyright != "" {
+		copyright = append(copyright, m.Copyright)
+	}
+	if m.License != "" {
+		copyright = append(copyright, fmt.Sprintf("License %s: <https://spdx.org/licenses/%s.html>.", m.License, m.License))
+	}
+	if len(copyright) != 0 {
+		sections["COPYRIGHT"] = &Section{
+			Title: "COPYRIGHT",
+			Text:  strings.Join(copyright, "\n\n"),
+		}
+	}
+	sections["SEE ALSO"] = &Section{
+		Title: "SEE ALSO",
+		Text:  "The documentation of the package is available at <https://pkg.go.dev/" + m.Package + ">.",
+	}
+	return sections
+}
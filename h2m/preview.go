@@ -0,0 +1,245 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// previewIndent is the indentation of the text of the sections.
+	previewIndent = 7
+	// previewSubIndent is the indentation of the subsection headings.
+	previewSubIndent = 3
+)
+
+// previewWord is a word made of spans, which is never split when wrapping.
+type previewWord []Span
+
+// len returns the visible length of the word.
+func (w previewWord) len() int {
+	n := 0
+	for _, s := range w {
+		n += utf8.RuneCountInString(s.Text)
+	}
+	return n
+}
+
+// previewer renders a parsed man page as text.
+type previewer struct {
+	w     io.Writer
+	width int
+	ansi  bool
+}
+
+// style returns the given text in the given font, with ANSI escape codes if
+// enabled. Like man(1), the italic is rendered underlined.
+func (p *previewer) style(text string, font Font) string {
+	if !p.ansi || text == "" {
+		return text
+	}
+	switch font {
+	case FontBold:
+		return "\x1b[1m" + text + "\x1b[0m"
+	case FontItalic:
+		return "\x1b[4m" + text + "\x1b[0m"
+	default:
+		return text
+	}
+}
+
+// render returns the given spans as text.
+func (p *previewer) render(spans []Span) string {
+	var b strings.Builder
+	for _, s := range spans {
+		b.WriteString(p.style(s.Text, s.Font))
+	}
+	return b.String()
+}
+
+// words splits a line in words, with nil words for its line breaks.
+func words(line Line) (words []previewWord) {
+	var word previewWord
+	flush := func() {
+		if len(word) != 0 {
+			words = append(words, word)
+			word = nil
+		}
+	}
+	for _, s := range line {
+		if s.Break {
+			flush()
+			words = append(words, nil)
+			continue
+		}
+		for i, f := range strings.Split(s.Text, " ") {
+			if i != 0 {
+				flush()
+			}
+			if f != "" {
+				word = append(word, Span{Text: f, Font: s.Font})
+			}
+		}
+	}
+	flush()
+	return
+}
+
+// wrap writes the given lines filled at the width of the previewer, indented
+// by indent. The first line is preceded by start spaces instead, so that it
+// can follow a tag. It reports whether anything was written.
+func (p *previewer) wrap(lines []Line, start, indent int) bool {
+	col := indent
+	empty, indented := true, false
+	written := false
+	pad := start
+	newline := func() {
+		mfprintln(p.w)
+		col, empty, indented, pad = indent, true, false, indent
+	}
+	for i, l := range lines {
+		if i != 0 && !empty {
+			newline()
+		}
+		for _, word := range words(l) {
+			if word == nil {
+				newline()
+				continue
+			}
+			n := word.len()
+			if !empty && col+1+n > p.width {
+				newline()
+			}
+			if !indented {
+				mfprint(p.w, strings.Repeat(" ", pad))
+				indented, written = true, true
+			}
+			if !empty {
+				mfprint(p.w, " ")
+				col++
+			}
+			mfprint(p.w, p.render(word))
+			col += n
+			empty = false
+		}
+	}
+	if !empty {
+		mfprintln(p.w)
+	}
+	return written
+}
+
+// line writes a line made of a left, a centred and a right text.
+func (p *previewer) line(left, center, right string) {
+	l, c, r := utf8.RuneCountInString(left), utf8.RuneCountInString(center), utf8.RuneCountInString(right)
+	lpad := (p.width-c)/2 - l
+	if lpad < 1 {
+		lpad = 1
+	}
+	rpad := p.width - l - lpad - c - r
+	if rpad < 1 {
+		rpad = 1
+	}
+	mfprintln(p.w, left+strings.Repeat(" ", lpad)+center+strings.Repeat(" ", rpad)+right)
+}
+
+// blocks writes the blocks of a section.
+func (p *previewer) blocks(blocks []*Block) {
+	// Like in roff, the width of a tagged paragraph is kept for the next ones
+	// until a new paragraph or heading.
+	width := previewIndent
+	for i, b := range blocks {
+		indent := previewIndent * (b.Indent + 1)
+		if i != 0 && (b.Kind == BlockTagged || blocks[i-1].Kind != BlockHeading) {
+			mfprintln(p.w)
+		}
+		if b.Kind == BlockHeading || b.Kind == BlockParagraph && b.Indent == 0 {
+			width = previewIndent
+		}
+		switch b.Kind {
+		case BlockHeading:
+			mfprintf(p.w, "%s%s\n", strings.Repeat(" ", previewSubIndent), p.style(b.Tag.String(), FontBold))
+		case BlockTagged:
+			if b.Width != 0 {
+				width = b.Width
+			}
+			var rendered []string
+			tagLen := 0
+			for i, w := range words(b.Tag) {
+				if i != 0 {
+					tagLen++
+				}
+				tagLen += w.len()
+				rendered = append(rendered, p.render(w))
+			}
+			mfprint(p.w, strings.Repeat(" ", indent)+strings.Join(rendered, " "))
+			if tagLen < width && p.wrap(b.Lines, width-tagLen, indent+width) {
+				continue
+			}
+			mfprintln(p.w)
+			if tagLen >= width {
+				p.wrap(b.Lines, indent+width, indent+width)
+			}
+		case BlockPreformatted:
+			for _, l := range b.Lines {
+				mfprintf(p.w, "%s%s\n", strings.Repeat(" ", indent), p.render(l))
+			}
+		default:
+			p.wrap(b.Lines, indent, indent)
+		}
+	}
+}
+
+// WritePreview writes in w the man page of the given roff source, formatted
+// as text filled at the given width, like man(1) would. The bold and italic
+// fonts are rendered with ANSI escape codes if ansi is true.
+//
+// Only the subset of man(7) written by [Write] and commonly found in include
+// files is supported, see [ParseRoff].
+func WritePreview(w io.Writer, source string, width int, ansi bool) (err error) {
+	defer func() {
+		if !debugMode {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("%v", p)
+			}
+		}
+	}()
+
+	d := ParseRoff(source)
+	p := &previewer{w: w, width: width, ansi: ansi}
+	title := make([]string, 5)
+	copy(title, d.Title)
+	page := title[0]
+	if title[1] != "" {
+		page += "(" + title[1] + ")"
+	}
+	p.line(page, title[4], page)
+	for _, s := range d.Sections {
+		mfprintln(w)
+		if s.Title != "" {
+			mfprintln(w, p.style(s.Title, FontBold))
+		}
+		p.blocks(s.Blocks)
+	}
+	mfprintln(w)
+	p.line(title[3], title[2], page)
+	return
+}
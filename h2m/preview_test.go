@@ -0,0 +1,85 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWritePreview(t *testing.T) {
+	source := `.TH TEST 1 2006-01-02 "test 1.0" "User Commands"
+.SH NAME
+test \- a test
+.SH OPTIONS
+.TP
+\fB\-o\fR FILE
+Write the output to FILE instead of the standard output, which is the default.
+.TP
+\fB\-v\fR
+Be verbose.
+.br
+Really.
+.SS Examples:
+.nf
+test \-o out
+.fi
+.IP \(bu 2
+Item.
+`
+	cases := []struct {
+		name     string
+		ansi     bool
+		expected string
+	}{
+		{"plain", false, `TEST(1)                User Commands                 TEST(1)
+
+NAME
+       test - a test
+
+OPTIONS
+       -o FILE
+              Write the output to FILE instead of the
+              standard output, which is the default.
+
+       -v     Be verbose.
+              Really.
+
+   Examples:
+       test -o out
+
+       • Item.
+
+test 1.0                 2006-01-02                  TEST(1)
+`},
+		{"ansi", true, "TEST(1)                User Commands                 TEST(1)\n\n" +
+			"\x1b[1mNAME\x1b[0m\n       test - a test\n\n" +
+			"\x1b[1mOPTIONS\x1b[0m\n       \x1b[1m-o\x1b[0m FILE\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := WritePreview(&b, source, 60, c.ansi); err != nil {
+				t.Fatal(err)
+			}
+			if actual := b.String(); !strings.HasPrefix(actual, c.expected) {
+				t.Fatalf("expected:\n%s\ngot:\n%s", c.expected, actual)
+			}
+		})
+	}
+}
@@ -17,7 +17,7 @@
 
 //go:build go1.24
 
-package main
+package h2m
 
 import (
 	"regexp"
@@ -15,10 +15,10 @@
 // You should have received a copy of the GNU General Public License
 // along with this program; if not, see <https://www.gnu.org/licenses/>.
 
-package main_test
+package h2m_test
 
 import (
-	main "github.com/n-peugnet/gohelp2man"
+	"github.com/n-peugnet/gohelp2man/h2m"
 	"testing"
 )
 
@@ -62,7 +62,7 @@ func TestRegexpReplacer(t *testing.T) {
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			replacer := main.NewRegexpReplacer(c.repls...)
+			replacer := h2m.NewRegexpReplacer(c.repls...)
 			output := replacer.Replace(c.input)
 			if output != c.expected {
 				t.Logf("input: %q, repls: %q", c.input, c.repls)
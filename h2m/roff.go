@@ -0,0 +1,359 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Font is the font of a span of text.
+type Font byte
+
+const (
+	FontRoman  Font = 'R'
+	FontBold   Font = 'B'
+	FontItalic Font = 'I'
+)
+
+// Span is a span of text in a single font. A span with an empty text and
+// Break set is a line break.
+type Span struct {
+	Text  string
+	Font  Font
+	Break bool
+}
+
+// Line is a line of text, made of spans.
+type Line []Span
+
+// String returns the text of the line without fonts.
+func (l Line) String() string {
+	var b strings.Builder
+	for _, s := range l {
+		b.WriteString(s.Text)
+	}
+	return b.String()
+}
+
+// BlockKind is the kind of a block of a man page.
+type BlockKind int
+
+const (
+	// BlockParagraph is a paragraph of filled text.
+	BlockParagraph BlockKind = iota
+	// BlockHeading is a subsection heading.
+	BlockHeading
+	// BlockTagged is a paragraph with a tag, e.g. an option and its
+	// description.
+	BlockTagged
+	// BlockPreformatted is a block of lines written as is.
+	BlockPreformatted
+)
+
+// Block is a block of a man page.
+type Block struct {
+	Kind BlockKind
+	// Tag is the tag of a BlockTagged, or the text of a BlockHeading.
+	Tag Line
+	// Lines are the lines of text of the block, in the order of the source.
+	Lines []Line
+	// Indent is the number of relative indentations (.RS) of the block.
+	Indent int
+	// Width is the indentation of the body of a BlockTagged, in ens, or 0
+	// if not given.
+	Width int
+}
+
+// DocSection is a section of a man page.
+type DocSection struct {
+	Title  string
+	Blocks []*Block
+}
+
+// Document is a man page parsed from its roff source, limited to the subset
+// of man(7) requests written by gohelp2man and commonly found in include
+// files.
+type Document struct {
+	// Title holds the arguments of the .TH request.
+	Title    []string
+	Sections []*DocSection
+}
+
+// roffEscapes are the named characters and escapes of roff recognised in
+// the text.
+var roffEscapes = map[string]string{
+	"-": "-", "e": `\`, "\\": `\`, "&": "", " ": " ", "~": " ", "|": "", "^": "",
+	"(rs": `\`, "(dq": `"`, "(aq": "'", "(bu": "•", "(co": "©", "(rg": "®",
+	"(em": "—", "(en": "–", "(hy": "-", "(lq": "“", "(rq": "”", "(oq": "‘",
+	"(cq": "’", "(mi": "-", "(ti": "~", "(ha": "^", "(lB": "[", "(rB": "]",
+	"(lC": "{", "(rC": "}", "(la": "⟨", "(ra": "⟩",
+	"*(lq": "“", "*(rq": "”", "*R": "®", "*(Tm": "™",
+}
+
+// parseRoffText parses the escapes of a line of roff text, starting in the
+// given font, and returns its spans and the font at its end.
+func parseRoffText(text string, font Font) (Line, Font) {
+	var line Line
+	var b strings.Builder
+	prev := FontRoman
+	flush := func() {
+		if b.Len() != 0 {
+			line = append(line, Span{Text: b.String(), Font: font})
+			b.Reset()
+		}
+	}
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c != '\\' || i+1 == len(text) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch text[i] {
+		case 'f':
+			if i+1 == len(text) {
+				continue
+			}
+			i++
+			var f string
+			if text[i] == '(' && i+2 < len(text) {
+				f = text[i+1 : i+3]
+				i += 2
+			} else {
+				f = text[i : i+1]
+			}
+			flush()
+			switch f {
+			case "B", "CB":
+				prev, font = font, FontBold
+			case "I", "CI":
+				prev, font = font, FontItalic
+			case "P":
+				prev, font = font, prev
+			default:
+				prev, font = font, FontRoman
+			}
+			continue
+		case '(':
+			if i+2 < len(text) {
+				b.WriteString(roffEscapes[text[i:i+3]])
+				i += 2
+			}
+			continue
+		case '*':
+			if i+3 < len(text) && text[i+1] == '(' {
+				b.WriteString(roffEscapes[text[i:i+4]])
+				i += 3
+			} else if i+1 < len(text) {
+				b.WriteString(roffEscapes[text[i:i+2]])
+				i++
+			}
+			continue
+		case '"':
+			// Comment until the end of the line.
+			i = len(text)
+			continue
+		}
+		if s, found := roffEscapes[text[i:i+1]]; found {
+			b.WriteString(s)
+		} else {
+			b.WriteByte(text[i])
+		}
+	}
+	flush()
+	return line, font
+}
+
+// parseRoffArgs splits the arguments of a request, which can be quoted.
+func parseRoffArgs(s string) (args []string) {
+	s = strings.TrimSpace(s)
+	for s != "" {
+		var arg string
+		if s[0] == '"' {
+			end := strings.Index(s[1:], `"`)
+			if end == -1 {
+				arg, s = s[1:], ""
+			} else {
+				arg, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(s, " \t")
+			if end == -1 {
+				arg, s = s, ""
+			} else {
+				arg, s = s[:end], s[end:]
+			}
+		}
+		args = append(args, arg)
+		s = strings.TrimLeft(s, " \t")
+	}
+	return
+}
+
+// parseRoffWidth parses an indentation argument in ens, e.g. "4" or "4n". It
+// returns 0 for the other units.
+func parseRoffWidth(arg string) int {
+	n, err := strconv.Atoi(strings.TrimSuffix(arg, "n"))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// fontRequests are the font requests, with the fonts they alternate.
+var fontRequests = map[string][2]Font{
+	"B":  {FontBold, FontBold},
+	"I":  {FontItalic, FontItalic},
+	"BR": {FontBold, FontRoman},
+	"RB": {FontRoman, FontBold},
+	"BI": {FontBold, FontItalic},
+	"IB": {FontItalic, FontBold},
+	"IR": {FontItalic, FontRoman},
+	"RI": {FontRoman, FontItalic},
+}
+
+// ParseRoff parses the given man page roff source.
+func ParseRoff(source string) *Document {
+	d := &Document{}
+	var section *DocSection
+	var block *Block
+	indent := 0
+	nofill := false
+	tagNext := false
+	font := FontRoman
+
+	addBlock := func(kind BlockKind) *Block {
+		if section == nil {
+			section = &DocSection{}
+			d.Sections = append(d.Sections, section)
+		}
+		block = &Block{Kind: kind, Indent: indent}
+		section.Blocks = append(section.Blocks, block)
+		return block
+	}
+	addLine := func(line Line, join bool) {
+		if len(line) == 0 && join {
+			return
+		}
+		if tagNext {
+			block.Tag = line
+			tagNext = false
+			return
+		}
+		if block == nil || block.Kind == BlockHeading || nofill != (block.Kind == BlockPreformatted) {
+			kind := BlockParagraph
+			if nofill {
+				kind = BlockPreformatted
+			}
+			addBlock(kind)
+		}
+		n := len(block.Lines)
+		if join && n != 0 && !nofill && !endsWithBreak(block.Lines[n-1]) {
+			block.Lines[n-1] = append(block.Lines[n-1], Span{Text: " ", Font: FontRoman})
+			block.Lines[n-1] = append(block.Lines[n-1], line...)
+			return
+		}
+		block.Lines = append(block.Lines, line)
+	}
+
+	for _, raw := range strings.Split(strings.TrimSuffix(source, "\n"), "\n") {
+		if !strings.HasPrefix(raw, ".") && !strings.HasPrefix(raw, "'") {
+			if raw == "" && !nofill {
+				block = nil
+				continue
+			}
+			var line Line
+			line, font = parseRoffText(raw, font)
+			// Like in roff, a leading space breaks the line.
+			addLine(line, !nofill && !strings.HasPrefix(raw, " "))
+			continue
+		}
+		name, rest, _ := strings.Cut(strings.TrimSpace(raw[1:]), " ")
+		args := parseRoffArgs(rest)
+		switch name {
+		case `\"`, "":
+		case "TH":
+			d.Title = args
+		case "SH":
+			section = &DocSection{Title: strings.Join(args, " ")}
+			d.Sections = append(d.Sections, section)
+			block, indent = nil, 0
+		case "SS":
+			text, _ := parseRoffText(strings.Join(args, " "), FontRoman)
+			addBlock(BlockHeading).Tag = text
+		case "PP", "P", "LP", "sp":
+			block = nil
+		case "TP":
+			b := addBlock(BlockTagged)
+			if len(args) != 0 {
+				b.Width = parseRoffWidth(args[0])
+			}
+			tagNext = true
+		case "IP":
+			b := addBlock(BlockTagged)
+			if len(args) != 0 {
+				b.Tag, _ = parseRoffText(args[0], FontRoman)
+			}
+			if len(args) > 1 {
+				b.Width = parseRoffWidth(args[1])
+			}
+		case "RS":
+			indent++
+			block = nil
+		case "RE":
+			if indent > 0 {
+				indent--
+			}
+			block = nil
+		case "nf":
+			nofill = true
+			block = nil
+		case "fi":
+			nofill = false
+			block = nil
+		case "br":
+			if block != nil && len(block.Lines) != 0 {
+				n := len(block.Lines) - 1
+				block.Lines[n] = append(block.Lines[n], Span{Break: true})
+			}
+		default:
+			fonts, found := fontRequests[name]
+			if !found {
+				continue
+			}
+			var line Line
+			for i, arg := range args {
+				spans, _ := parseRoffText(arg, fonts[i%2])
+				if name == "B" || name == "I" {
+					if i != 0 {
+						line = append(line, Span{Text: " ", Font: FontRoman})
+					}
+				}
+				line = append(line, spans...)
+			}
+			addLine(line, !nofill)
+		}
+	}
+	return d
+}
+
+// endsWithBreak reports whether line ends with a line break.
+func endsWithBreak(line Line) bool {
+	return len(line) != 0 && line[len(line)-1].Break
+}
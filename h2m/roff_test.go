@@ -0,0 +1,114 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRoffText(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected Line
+		font     Font
+	}{
+		{"plain", `a \- b`, Line{{Text: "a - b", Font: FontRoman}}, FontRoman},
+		{"fonts", `\fBa\fP b \fIc\fR`, Line{{Text: "a", Font: FontBold}, {Text: " b ", Font: FontRoman}, {Text: "c", Font: FontItalic}}, FontRoman},
+		{"unclosed", `\fBa`, Line{{Text: "a", Font: FontBold}}, FontBold},
+		{"named", `\(bu \(dq\(rs\e`, Line{{Text: `• "\\`, Font: FontRoman}}, FontRoman},
+		{"comment", `a \" comment`, Line{{Text: "a ", Font: FontRoman}}, FontRoman},
+		{"zero width", `\&.b`, Line{{Text: ".b", Font: FontRoman}}, FontRoman},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			line, font := parseRoffText(c.input, FontRoman)
+			if !reflect.DeepEqual(c.expected, line) {
+				t.Errorf("expected line: %#v, got: %#v", c.expected, line)
+			}
+			if c.font != font {
+				t.Errorf("expected font: %c, got: %c", c.font, font)
+			}
+		})
+	}
+}
+
+func TestParseRoff(t *testing.T) {
+	input := `.\" Comment
+.TH TEST 1 2006-01-02 "test 1.0"
+.SH NAME
+test \- a test
+.SH OPTIONS
+.SS Flags:
+.TP
+.BR \-o " FILE"
+Write to
+FILE.
+.br
+Really.
+.RS
+.nf
+a  b
+.fi
+.RE
+.IP \(bu 2
+Item.
+.PP
+.I End
+`
+	r := func(s string) Span { return Span{Text: s, Font: FontRoman} }
+	expected := &Document{
+		Title: []string{"TEST", "1", "2006-01-02", "test 1.0"},
+		Sections: []*DocSection{
+			{Title: "NAME", Blocks: []*Block{
+				{Kind: BlockParagraph, Lines: []Line{{r("test - a test")}}},
+			}},
+			{Title: "OPTIONS", Blocks: []*Block{
+				{Kind: BlockHeading, Tag: Line{r("Flags:")}},
+				{
+					Kind:  BlockTagged,
+					Tag:   Line{{Text: "-o", Font: FontBold}, r(" FILE")},
+					Lines: []Line{{r("Write to"), r(" "), r("FILE."), {Break: true}}, {r("Really.")}},
+				},
+				{Kind: BlockPreformatted, Lines: []Line{{r("a  b")}}, Indent: 1},
+				{Kind: BlockTagged, Tag: Line{r("•")}, Lines: []Line{{r("Item.")}}, Width: 2},
+				{Kind: BlockParagraph, Lines: []Line{{{Text: "End", Font: FontItalic}}}},
+			}},
+		},
+	}
+	actual := ParseRoff(input)
+	if !reflect.DeepEqual(expected.Title, actual.Title) {
+		t.Errorf("expected title: %q, got: %q", expected.Title, actual.Title)
+	}
+	if len(expected.Sections) != len(actual.Sections) {
+		t.Fatalf("expected %d sections, got %d", len(expected.Sections), len(actual.Sections))
+	}
+	for i, s := range expected.Sections {
+		if !reflect.DeepEqual(s, actual.Sections[i]) {
+			t.Errorf("section %s: expected:", s.Title)
+			for _, b := range s.Blocks {
+				t.Errorf("%+v", *b)
+			}
+			t.Errorf("got:")
+			for _, b := range actual.Sections[i].Blocks {
+				t.Errorf("%+v", *b)
+			}
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/constant"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// flagFunc describes a function or method of the "flag" package that defines
+// a flag, with the position of its arguments (-1 if absent) and its kind.
+type flagFunc struct {
+	name, value, usage int
+	kind               string
+}
+
+var flagFuncs = map[string]flagFunc{
+	"Bool":        {0, 1, 2, "bool"},
+	"BoolVar":     {1, 2, 3, "bool"},
+	"BoolFunc":    {0, -1, 1, "bool"},
+	"Duration":    {0, 1, 2, "duration"},
+	"DurationVar": {1, 2, 3, "duration"},
+	"Float64":     {0, 1, 2, "float"},
+	"Float64Var":  {1, 2, 3, "float"},
+	"Func":        {0, -1, 1, "value"},
+	"Int":         {0, 1, 2, "int"},
+	"IntVar":      {1, 2, 3, "int"},
+	"Int64":       {0, 1, 2, "int"},
+	"Int64Var":    {1, 2, 3, "int"},
+	"String":      {0, 1, 2, "string"},
+	"StringVar":   {1, 2, 3, "string"},
+	"TextVar":     {1, -1, 3, "value"},
+	"Uint":        {0, 1, 2, "uint"},
+	"UintVar":     {1, 2, 3, "uint"},
+	"Uint64":      {0, 1, 2, "uint"},
+	"Uint64Var":   {1, 2, 3, "uint"},
+	"Var":         {1, -1, 2, "value"},
+}
+
+// unquoteUsage is [flag.UnquoteUsage] for a flag of the given kind.
+func unquoteUsage(kind, usage string) (arg string, unquoted string) {
+	if start := strings.IndexByte(usage, '`'); start != -1 {
+		if end := strings.IndexByte(usage[start+1:], '`'); end != -1 {
+			end += start + 1
+			arg = usage[start+1 : end]
+			return arg, usage[:start] + arg + usage[end+1:]
+		}
+	}
+	if kind == "bool" {
+		return "", usage
+	}
+	return kind, usage
+}
+
+// defValue returns the default value of a flag of the given kind, formatted
+// like by [flag.PrintDefaults]. It is empty if v is unknown or the zero
+// value.
+func defValue(kind string, v constant.Value) (def string, quote bool) {
+	if v == nil {
+		return "", false
+	}
+	switch kind {
+	case "bool":
+		if v.Kind() == constant.Bool && constant.BoolVal(v) {
+			return "true", false
+		}
+	case "duration":
+		if d, ok := constant.Int64Val(constant.ToInt(v)); ok && d != 0 {
+			return time.Duration(d).String(), false
+		}
+	case "float":
+		if f, _ := constant.Float64Val(constant.ToFloat(v)); f != 0 {
+			return strconv.FormatFloat(f, 'g', -1, 64), false
+		}
+	case "int", "uint":
+		if i := constant.ToInt(v); i.Kind() == constant.Int && constant.Sign(i) != 0 {
+			return i.ExactString(), false
+		}
+	case "string":
+		if v.Kind() == constant.String && constant.StringVal(v) != "" {
+			return constant.StringVal(v), true
+		}
+	}
+	return "", false
+}
+
+// isBoolValue reports whether t implements the optional IsBoolFlag method
+// of flag values, used by [flag.Var] to define boolean flags.
+func isBoolValue(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, "IsBoolFlag")
+	_, ok := obj.(*types.Func)
+	return ok
+}
+
+// importPackage returns the Go package found at path, which can be an import
+// path or a directory, relative to the current directory.
+func importPackage(path string, mode build.ImportMode) (*build.Package, error) {
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		return build.ImportDir(path, mode)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return build.Import(path, wd, mode)
+}
+
+// loadPackage parses and type-checks the Go package found at path, which
+// can be an import path or a directory, relative to the current directory.
+// Type errors are ignored, as long as the package can be parsed.
+func loadPackage(path string) ([]*ast.File, *types.Info, error) {
+	pkg, err := importPackage(path, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range append(pkg.GoFiles, pkg.CgoFiles...) {
+		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, f)
+	}
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{
+		Importer:    importer.ForCompiler(fset, "source", nil),
+		FakeImportC: true,
+		Error:       func(error) {},
+	}
+	conf.Check(pkg.ImportPath, fset, files, info)
+	return files, info, nil
+}
+
+// FromPackage returns the Help of the flags defined in the Go package found at
+// path, without building nor running it. The package can be given as an import
+// path or a directory. Its source is parsed and type-checked to find the calls
+// to the functions and methods of the "flag" package that define flags, whose
+// name, default value and usage are extracted when they are constant. Like
+// with [flag.PrintDefaults], the flags are sorted by name.
+func FromPackage(path string) (*Help, error) {
+	files, info, err := loadPackage(path)
+	if err != nil {
+		return nil, fmt.Errorf("load package: %w", err)
+	}
+	h := &Help{Sections: make(map[string]*Section)}
+	found := make(map[string]bool)
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			var id *ast.Ident
+			switch fun := call.Fun.(type) {
+			case *ast.Ident:
+				id = fun
+			case *ast.SelectorExpr:
+				id = fun.Sel
+			default:
+				return true
+			}
+			fn, ok := info.Uses[id].(*types.Func)
+			if !ok || fn.Pkg() == nil || fn.Pkg().Path() != "flag" {
+				return true
+			}
+			ff, ok := flagFuncs[fn.Name()]
+			if !ok || len(call.Args) <= ff.usage {
+				return true
+			}
+			name := info.Types[call.Args[ff.name]].Value
+			if name == nil || name.Kind() != constant.String {
+				return true
+			}
+			var usage string
+			if v := info.Types[call.Args[ff.usage]].Value; v != nil && v.Kind() == constant.String {
+				usage = constant.StringVal(v)
+			}
+			kind := ff.kind
+			if fn.Name() == "Var" && isBoolValue(info.Types[call.Args[0]].Type) {
+				kind = "bool"
+			}
+			var def string
+			var quote bool
+			if ff.value != -1 {
+				def, quote = defValue(kind, info.Types[call.Args[ff.value]].Value)
+			}
+			flagName := constant.StringVal(name)
+			if found[flagName] {
+				return true
+			}
+			found[flagName] = true
+			arg, usage := unquoteUsage(kind, usage)
+			h.Flags = append(h.Flags, newFlag(flagName, arg, usage, def, quote))
+			return true
+		})
+	}
+	sort.Slice(h.Flags, func(i, j int) bool { return h.Flags[i].Name < h.Flags[j].Name })
+	return h, nil
+}
@@ -0,0 +1,57 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFromPackage(t *testing.T) {
+	help, err := FromPackage("./testdata/static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"-color Colorize the output. (default true)",
+		"-exec value Run command.",
+		"-include FILE Include FILE.",
+		"-n int Number of greetings.\nCan be repeated. (default 1)",
+		"-name NAME The NAME to greet. (default \"world\")",
+		"-o FILE Write the output to FILE.",
+		"-q Do not print anything.",
+		"-ratio float Ratio. (default 0.5)",
+		"-timeout duration Maximum duration. (default 5s)",
+		"-v Enable verbose output.",
+	}
+	var actual []string
+	for _, f := range help.Flags {
+		s := "-" + f.Name
+		if f.Arg != "" {
+			s += " " + f.Arg
+		}
+		actual = append(actual, s+" "+f.Usage)
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+
+	if _, err := FromPackage("./testdata/nonexistent"); err == nil {
+		t.Fatal("expected an error for a nonexistent package")
+	}
+}
@@ -0,0 +1,23 @@
+// Command doc greets people, see [Greetings] and the [flag] package.
+//
+// It reads the names from the standard input:
+//
+//	echo world | doc -n 2
+//
+// # Options
+//
+// The options are:
+//   - -n: the number of greetings.
+//   - -name: the name to greet,
+//     if not read from the input.
+//
+// # Exit status
+//
+// It exits with:
+//  1. 0 on success;
+//  2. 1 on error.
+//
+// [Greetings]: https://example.com/greetings
+package main
+
+func main() {}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultName = "world"
+
+type listValue []string
+
+func (l *listValue) String() string     { return strings.Join(*l, ",") }
+func (l *listValue) Set(s string) error { *l = append(*l, s); return nil }
+
+type boolValue bool
+
+func (b *boolValue) String() string     { return fmt.Sprint(bool(*b)) }
+func (b *boolValue) Set(s string) error { *b = s == "true"; return nil }
+func (b *boolValue) IsBoolFlag() bool   { return true }
+
+func main() {
+	var (
+		n       int
+		quiet   boolValue
+		include listValue
+	)
+	verbose := flag.Bool("v", false, "Enable verbose output.")
+	flag.Bool("color", true, "Colorize the output.")
+	flag.IntVar(&n, "n", 1, "Number of greetings.\nCan be repeated.")
+	flag.Var(&quiet, "q", "Do not print anything.")
+	flag.Var(&include, "include", "Include `FILE`.")
+	fs := flag.NewFlagSet("main", flag.ExitOnError)
+	name := fs.String("name", defaultName, "The `NAME` to greet.")
+	fs.String("o", "", "Write the output to `FILE`.")
+	fs.Duration("timeout", 5*time.Second, "Maximum duration.")
+	fs.Float64("ratio", 0.5, "Ratio.")
+	fs.Func("exec", "Run "+"command.", func(string) error { return nil })
+	flag.Parse()
+	fs.Parse(os.Args[1:])
+	if *verbose {
+		fmt.Println(n, *name)
+	}
+}
@@ -0,0 +1,377 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var blockEscaper = NewRegexpReplacer(
+	`-`, `\-`,
+	`\\`, `\(rs`,
+	"\n\n+", "\n.PP\n",
+	`(?m)^\.`, `\&.`,
+	`(?m)^\'`, `\&'`,
+)
+
+var inlineFormats = []string{
+	// Format man(1) style notation
+	`\b(\w|\w(?:\\-|\w|\.|:)*\w)\((\w+)\)\B`, `\fB$1\fP($2)`,
+	// Format -flag in bold
+	`\B(\\-(?:\\-|\w)*\w)\b`, `\fB$1\fP`,
+}
+
+var blockFormatter = NewRegexpReplacer(append([]string{
+	// Format second level headers
+	`(?m)^(?:.PP\n)?(\w.*):\s*$`, `.SS $1:`,
+}, inlineFormats...)...)
+
+var inlineFormatter = NewRegexpReplacer(inlineFormats...)
+
+var fieldEscaper = NewRegexpReplacer(
+	`-`, `\-`,
+	`"`, `\(dq`,
+)
+
+// must panics if err is not nil.
+func must(n int, err error) int {
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// mfprint is [fmt.Fprint] wrapped with [must].
+func mfprint(w io.Writer, args ...any) int {
+	return must(fmt.Fprint(w, args...))
+}
+
+// mfprintln is [fmt.Fprintln] wrapped with [must].
+func mfprintln(w io.Writer, args ...any) int {
+	return must(fmt.Fprintln(w, args...))
+}
+
+// mfprintf is [fmt.Fprintf] wrapped with [must].
+func mfprintf(w io.Writer, format string, args ...any) int {
+	return must(fmt.Fprintf(w, format, args...))
+}
+
+// e escapes and formats a value to be included as is in a man page as a block of text.
+func e(v any) string {
+	escaped := blockEscaper.Replace(fmt.Sprint(v))
+	return blockFormatter.Replace(escaped)
+}
+
+func eArgs(args []any) []any {
+	eargs := make([]any, len(args))
+	for i, arg := range args {
+		eargs[i] = e(arg)
+	}
+	return eargs
+}
+
+// efprint is [mfprint] with all args escaped with [e].
+func efprint(w io.Writer, args ...any) int {
+	return mfprint(w, eArgs(args)...)
+}
+
+// efprintln is [mfprintln] with all args escaped with [e].
+func efprintln(w io.Writer, args ...any) int {
+	return mfprintln(w, eArgs(args)...)
+}
+
+// efprintf is [mfprintf] with all args escaped with [e].
+func efprintf(w io.Writer, format string, args ...any) int {
+	return mfprintf(w, format, eArgs(args)...)
+}
+
+// sectionMarkup returns the text of a known section if found, ready to be
+// written on the output man page as is. The text of the first of the given
+// patterns matching a paragraph is inserted after it.
+func (h *Help) sectionMarkup(title string, patterns []*Pattern) (markup string, found bool) {
+	s, found := h.Sections[title]
+	b := &strings.Builder{}
+	if found {
+		writeParagraphs(b, s.Text, patterns)
+	}
+	switch title {
+	case "OPTIONS":
+		found = true
+		writeFlags(b, h.Flags, patterns, h.SeparateDefaults)
+	case "COMMANDS":
+		found = found || len(h.Commands) != 0
+		for _, c := range h.Commands {
+			efprintf(b, ".TP\n\\fB%s\\fR", c.Name)
+			for _, a := range c.Aliases {
+				efprintf(b, ", \\fB%s\\fR", a)
+			}
+			mfprintln(b)
+			efprintln(b, c.Usage)
+		}
+		for _, c := range h.Commands {
+			if c.Help == nil {
+				continue
+			}
+			efprintf(b, ".SS %s\n", c.Name)
+			if c.Help.Usage != "" {
+				writeSynopsis(b, c.Help.Usage)
+			}
+			writeFlags(b, c.Help.Flags, patterns, h.SeparateDefaults)
+		}
+	case "SEE ALSO":
+		if len(h.References) != 0 && found {
+			mfprintln(b, ".PP")
+		}
+		found = found || len(h.References) != 0
+		for i, ref := range h.References {
+			page, sect, _ := strings.Cut(strings.TrimSuffix(ref, ")"), "(")
+			mfprintf(b, ".BR %s (%s)", fieldEscaper.Replace(page), sect)
+			if i != len(h.References)-1 {
+				mfprint(b, ",")
+			}
+			mfprintln(b)
+		}
+	}
+	markup = b.String()
+	return
+}
+
+// writeFlags writes the given flags in w as a list of tagged paragraphs.
+// The text of the first pattern matching a flag is inserted after it. If
+// separateDefaults is true, default values are written in italic on their
+// own line.
+func writeFlags(w io.Writer, flags []*Flag, patterns []*Pattern, separateDefaults bool) {
+	for _, f := range flags {
+		mfprintln(w, ".TP")
+		names := append([]string{f.Name}, f.Aliases...)
+		for i, n := range names {
+			if i != 0 {
+				mfprint(w, ", ")
+			}
+			mfprintf(w, "\\fB\\-%s\\fR", blockEscaper.Replace(n))
+		}
+		efprintln(w, f.argSuffix())
+		if separateDefaults && f.Default != "" {
+			if usage := f.usageWithoutDefault(); usage != "" {
+				efprintln(w, usage)
+				mfprintln(w, ".br")
+			}
+			efprintf(w, "\\fIDefault: %s\\fR\n", f.Default)
+		} else {
+			efprintln(w, f.Usage)
+		}
+		writeMatching(w, "-"+strings.Join(names, ", -")+f.argSuffix()+"\n"+f.Usage, patterns)
+	}
+}
+
+var regexParagraphSep = regexp.MustCompile("\n\n+")
+
+// writeParagraphs escapes and writes text in w, paragraph by paragraph. The
+// text of the first pattern matching a paragraph is written after it.
+func writeParagraphs(w io.Writer, text string, patterns []*Pattern) {
+	for i, p := range regexParagraphSep.Split(text, -1) {
+		markup := e(p)
+		// Second level headers already start a new paragraph.
+		if i != 0 && !strings.HasPrefix(markup, ".SS ") {
+			mfprintln(w, ".PP")
+		}
+		mfprintln(w, markup)
+		writeMatching(w, p, patterns)
+	}
+}
+
+// writeMatching writes in w the text of all the patterns that match the given
+// paragraph and have not been inserted yet.
+func writeMatching(w io.Writer, paragraph string, patterns []*Pattern) {
+	for _, p := range patterns {
+		if !p.matched && p.Regexp.MatchString(paragraph) {
+			p.matched = true
+			mfprintln(w, p.Text)
+		}
+	}
+}
+
+// writeSynopsis formats a synopsis line by writing the command name in bold
+// and the arguments inside brackets in italic.
+func writeSynopsis(w io.Writer, synopsis string) {
+	name, rest, found := strings.Cut(strings.TrimSpace(synopsis), " ")
+	if !found {
+		efprintf(w, "\\fB%s\\fR\n", name)
+		return
+	}
+	splits := strings.Split(rest, "\n"+name+" ")
+	re := regexp.MustCompile(`\[([^[]+)\]`)
+	for i, args := range splits {
+		if i != 0 {
+			mfprint(w, ".br\n")
+		}
+		efprintf(w, "\\fB%s\\fR ", name)
+		mfprintln(w, re.ReplaceAllString(e(args), `[\fI${1}\fR]`))
+	}
+}
+
+// writeKnownSection writes the section with given title in w if it is present
+// at least in i or h. It withHeader is true and the section is found, then
+// the title of this section it prependend to the section's text.
+//
+// The text from i is written first, and if the section is present in both i
+// and h, then they will be in different paragraphs. The patterns of i are
+// inserted in the text from h, unless it is replaced.
+func writeKnownSection(w io.Writer, i *Include, h *Help, title string) {
+	si, foundi := i.Sections[title]
+	patterns := i.Patterns
+	if foundi && si.Pos == '=' {
+		patterns = nil
+	}
+	sh, foundh := h.sectionMarkup(title, patterns)
+	if !foundi && !foundh {
+		return
+	}
+	mfprintf(w, ".SH %s\n", title)
+	switch {
+	case foundi && foundh:
+		switch si.Pos {
+		case '>':
+			mfprint(w, sh)
+			mfprintln(w, ".PP")
+			mfprintln(w, si.Text)
+		case '=':
+			mfprintln(w, si.Text)
+		case '<':
+			fallthrough
+		default:
+			mfprintln(w, si.Text)
+			mfprintln(w, ".PP")
+			mfprint(w, sh)
+		}
+	case foundi:
+		mfprintln(w, si.Text)
+	case foundh:
+		mfprint(w, sh)
+	}
+}
+
+// Page holds the metadata of a man page.
+type Page struct {
+	// Name is the name of the program.
+	Name string
+	// Description is the text of the NAME section. Defaults to "manual page
+	// for Name".
+	Description string
+	// Version is the version of the program, written in the footer. If it
+	// is a single word, it is prefixed by Name.
+	Version string
+	// Section is the section of the manual. Defaults to "1".
+	Section string
+	// Manual is the name of the manual, omitted if empty.
+	Manual string
+}
+
+// footer returns the text of the footer of the page.
+func (p *Page) footer() string {
+	fields := strings.Fields(p.Version)
+	switch len(fields) {
+	case 0:
+		return p.Name
+	case 1:
+		return p.Name + " " + fields[0]
+	default:
+		return strings.Join(fields, " ")
+	}
+}
+
+// Write writes in w the man page p made of the given include and help. Both
+// of them may be nil. The same include can be written several times, its
+// patterns being matched again each time.
+func Write(w io.Writer, p *Page, include *Include, help *Help) (err error) {
+	defer func() {
+		if !debugMode {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("%v", p)
+			}
+		}
+	}()
+
+	if include == nil {
+		include = &Include{}
+	}
+	for _, pattern := range include.Patterns {
+		pattern.matched = false
+	}
+	if help == nil {
+		help = &Help{}
+	}
+	name, description, section := p.Name, p.Description, p.Section
+	if description == "" {
+		description = "manual page for " + name
+	}
+	if section == "" {
+		section = "1"
+	}
+
+	// Write generator comment
+	mfprintf(w, ".\\\" Generated by gohelp2man %s; DO NOT EDIT.\n", Version())
+
+	// Write title
+	mfprintf(w, `.TH %s %v %s "%s"`,
+		fieldEscaper.Replace(strings.ToUpper(name)),
+		section,
+		now().Format("2006-01-02"),
+		fieldEscaper.Replace(p.footer()),
+	)
+	if p.Manual != "" {
+		mfprintf(w, ` "%s"`, fieldEscaper.Replace(p.Manual))
+	}
+	mfprintln(w)
+
+	// Write NAME section
+	efprintf(w, ".SH NAME\n%v \\- %v\n", name, description)
+
+	// Write SYNOPSIS section
+	mfprintln(w, ".SH SYNOPSIS")
+	if s, found := include.Sections["SYNOPSIS"]; found {
+		mfprintln(w, s.Text)
+	} else if help.Usage != "" {
+		writeSynopsis(w, help.Usage)
+	} else {
+		efprintf(w, "\\fB%s\\fR [\\fIOPTION\\fR]... [\\fIARGUMENT\\fR]...\n", name)
+	}
+
+	// Write DESCRIPTION section
+	writeKnownSection(w, include, help, "DESCRIPTION")
+
+	// Write OPTIONS section
+	writeKnownSection(w, include, help, "OPTIONS")
+
+	// Write COMMANDS section
+	writeKnownSection(w, include, help, "COMMANDS")
+
+	// Write other included sections
+	for _, s := range include.OtherSections {
+		mfprintf(w, ".SH %s\n%s\n", s.Title, s.Text)
+	}
+
+	// Write last known sections
+	for _, title := range KnownSections[lastSections:] {
+		writeKnownSection(w, include, help, title)
+	}
+	return
+}
@@ -0,0 +1,120 @@
+// This file is part of gohelp2man.
+//
+// Copyright (C) 2025  Nicolas Peugnet <nicolas@club1.fr>
+//
+// gohelp2man is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// gohelp2man is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, see <https://www.gnu.org/licenses/>.
+
+package h2m
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteSynopsis(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"basic", "test [OPTION]... [ARGUMENT]...", `\fBtest\fR [\fIOPTION\fR]... [\fIARGUMENT\fR]...`},
+		{"non-closed brackets", "test [argument", `\fBtest\fR [argument`},
+		{"end with lbracket", "test argument[", `\fBtest\fR argument[`},
+		{"end with rbracket", "test argument]", `\fBtest\fR argument]`},
+		{"single bracketed arg", "test [argument]", `\fBtest\fR [\fIargument\fR]`},
+		{"no args", "test", `\fBtest\fR`},
+		{"no args with space", "test ", `\fBtest\fR`},
+		{"empty", "", `\fB\fR`},
+		{"single space", "", `\fB\fR`},
+		{"starts with space", " test args", `\fBtest\fR args`},
+		{
+			"basic multiline",
+			`stringer [flags] -type T [directory]
+stringer [flags] -type T files...`,
+			`\fBstringer\fR [\fIflags\fR] \fB\-type\fP T [\fIdirectory\fR]
+.br
+\fBstringer\fR [\fIflags\fR] \fB\-type\fP T files...`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := &strings.Builder{}
+			writeSynopsis(w, c.input)
+			actual := w.String()
+			if actual != c.expected+"\n" {
+				t.Fatalf("expected:\n%s\ngot:\n%s", c.expected+"\n", actual)
+			}
+		})
+	}
+}
+
+func TestWriteTwice(t *testing.T) {
+	include, err := ParseInclude(strings.NewReader("/-verbose/\nInserted.\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	help := &Help{Flags: []*Flag{{Name: "verbose", Usage: "Be verbose.", IsBool: true}}}
+	var outputs [2]strings.Builder
+	for i := range outputs {
+		if err := Write(&outputs[i], &Page{Name: "test"}, include, help); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !strings.Contains(outputs[0].String(), "Inserted.") {
+		t.Fatalf("pattern not inserted:\n%s", outputs[0].String())
+	}
+	if outputs[0].String() != outputs[1].String() {
+		t.Fatalf("expected:\n%s\ngot:\n%s", outputs[0].String(), outputs[1].String())
+	}
+	if unmatched := include.UnmatchedPatterns(); len(unmatched) != 0 {
+		t.Fatalf("expected no unmatched patterns, got %v", unmatched)
+	}
+}
+
+func TestWriteCommandSynopsis(t *testing.T) {
+	help := &Help{Commands: []*Command{
+		{Name: "build", Usage: "Build it.", Help: &Help{Usage: "build"}},
+	}}
+	include := &Include{Sections: map[string]*Section{}}
+	var b strings.Builder
+	if err := Write(&b, &Page{Name: "test"}, include, help); err != nil {
+		t.Fatal(err)
+	}
+	expected := ".SS build\n\\fBbuild\\fR\n"
+	if !strings.Contains(b.String(), expected) {
+		t.Fatalf("expected to contain:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
+
+func TestWriteFlags(t *testing.T) {
+	cases := []struct {
+		name     string
+		flag     *Flag
+		expected string
+	}{
+		{"bool", &Flag{Name: "v", Usage: "Be verbose."}, ".TP\n\\fB\\-v\\fR\nBe verbose.\n"},
+		{"arg", &Flag{Name: "o", Arg: "FILE", Usage: "Write to FILE."}, ".TP\n\\fB\\-o\\fR FILE\nWrite to FILE.\n"},
+		{"equal arg", &Flag{Name: "-count", Arg: "=N", Usage: "Repeat."}, ".TP\n\\fB\\-\\-count\\fR=N\nRepeat.\n"},
+		{"optional arg", &Flag{Name: "-color", Arg: "[=WHEN]", Usage: "Colorize."}, ".TP\n\\fB\\-\\-color\\fR[=WHEN]\nColorize.\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var b strings.Builder
+			writeFlags(&b, []*Flag{c.flag}, nil, false)
+			if b.String() != c.expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", c.expected, b.String())
+			}
+		})
+	}
+}
@@ -15,26 +15,26 @@
 // You should have received a copy of the GNU General Public License
 // along with this program; if not, see <https://www.gnu.org/licenses/>.
 
-//go:generate go build
-//go:generate go run . -version-string=v0.6.0 -include=gohelp2man.h2m -output=gohelp2man.1 ./gohelp2man
+//go:generate go run . -version-string=v0.6.0 -include=gohelp2man.h2m -output=gohelp2man.1 .
 
 package main
 
 import (
-	"bufio"
 	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"go/build"
 	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
-	"runtime/debug"
 	"strconv"
 	"strings"
-	"time"
+
+	"github.com/n-peugnet/gohelp2man/h2m"
 )
 
 const (
@@ -52,304 +52,194 @@ the help message generated by the "flag" package.
 It is a great match with "go get -tool" and "go generate"!
 
 Usage: %s [OPTION]... EXECUTABLE
+  or:  %s [OPTION]... PACKAGE
+  or:  %s [OPTION]... -help-file FILE
+  or:  %s [OPTION]... -config FILE
 `
-
-	RegexSection = `^\[([^]]+)\]\s*$`
-	RegexUsage   = `[Uu]sage(:| of) (?U:(.*)):?$`
-	RegexUsage2  = `^((\t|\s+or: )(.*)|  ([^-].*))$`
-	RegexHeader  = `^(\w.*):\s*$`
-	RegexFlag    = `^  -((\w)\t(.*)|([-\w]+) (.+)|[-\w]+)$`
-	RegexFUsage  = `^  [^-].*$`
-)
-
-var (
-	debugMode    = os.Getenv("GOH2M_DEBUG") != ""
-	l            = log.New(os.Stderr, Name+": ", 0)
-	regexSection = regexp.MustCompile(RegexSection)
-	regexUsage   = regexp.MustCompile(RegexUsage)
-	regexUsage2  = regexp.MustCompile(RegexUsage2)
-	regexHeader  = regexp.MustCompile(RegexHeader)
-	regexFlag    = regexp.MustCompile(RegexFlag)
-	regexFUsage  = regexp.MustCompile(RegexFUsage)
 )
 
-var KnownSections = [12]string{
-	"NAME",
-	"SYNOPSIS",
-	"DESCRIPTION",
-	"OPTIONS",
-	// Other
-	"ENVIRONMENT",
-	"FILES",
-	"EXAMPLES",
-	"AUTHOR",
-	"REPORTING BUGS",
-	"COPYRIGHT",
-	"SEE ALSO",
+// logger is a [log.Logger] whose fatal methods run the cleanups before
+// exiting.
+type logger struct {
+	*log.Logger
 }
 
-func findKnownSection(s string) (title string, found bool) {
-	title = strings.ToUpper(s)
-	switch title {
-	case "OPTIONS", "FLAGS":
-		title = "OPTIONS"
-		fallthrough
-	case "NAME",
-		"SYNOPSIS",
-		"DESCRIPTION",
-		"ENVIRONMENT",
-		"FILES",
-		"EXAMPLES",
-		"AUTHOR",
-		"REPORTING BUGS",
-		"COPYRIGHT",
-		"SEE ALSO":
-		found = true
-	}
-	return
+func (l logger) Fatalf(format string, v ...any) {
+	l.Output(2, fmt.Sprintf(format, v...))
+	exit(1)
 }
 
-type Section struct {
-	Title string
-	Text  string
-	Pos   byte
+func (l logger) Fatalln(v ...any) {
+	l.Output(2, fmt.Sprintln(v...))
+	exit(1)
 }
 
-func (s *Section) String() string {
-	return fmt.Sprintf("{%q %q %q}", s.Title, s.Text, s.Pos)
-}
+var l = logger{log.New(os.Stderr, Name+": ", 0)}
 
-type Flag struct {
-	Name  string
-	Arg   string
-	Usage string
-}
+// warnings is the number of warnings printed by warnf.
+var warnings int
 
-func (f *Flag) String() string {
-	return fmt.Sprintf("-%s %q: %s", f.Name, f.Arg, f.Usage)
+// warnf prints a warning and counts it.
+func warnf(format string, v ...any) {
+	warnings++
+	l.Output(2, "warning: "+fmt.Sprintf(format, v...))
 }
 
-type Help struct {
-	Usage    string
-	Flags    []*Flag
-	Sections map[string]*Section
-
-	scanner *bufio.Scanner
+// warnAll prints the given warnings found while parsing source.
+func warnAll(source string, ws []*h2m.Warning) {
+	for _, w := range ws {
+		warnf("%s: %v", source, w)
+	}
 }
 
-func NewHelp(help io.Reader) *Help {
-	return &Help{
-		Sections: make(map[string]*Section),
-		scanner:  bufio.NewScanner(help),
+// cleanups are run by cleanup, in reverse order.
+var cleanups []func()
+
+// cleanup runs the registered cleanups.
+func cleanup() {
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i]()
 	}
+	cleanups = nil
 }
 
-// parseUsage parses synopsis lines from the internal reader. It will continue
-// until the current line does not look like a synopsis/usage string, leaving
-// the current line to be parsed.
-func (h *Help) parseUsage() {
-	var text strings.Builder
-	line := h.scanner.Bytes()
-	m := regexUsage.FindSubmatch(line)
-	if m != nil {
-		if bytes.IndexRune(m[2], ' ') != -1 {
-			text.Write(m[2])
-		}
-		for h.scanner.Scan() {
-			m = regexUsage2.FindSubmatch(h.scanner.Bytes())
-			if m != nil {
-				text.WriteString("\n")
-				text.Write(bytes.TrimSpace(m[3]))
-				text.Write(bytes.TrimSpace(m[4]))
-			} else {
-				break
-			}
-		}
-		h.Usage = strings.TrimSpace(text.String())
-	}
+// exit runs the cleanups, then exits with the given status code.
+func exit(code int) {
+	cleanup()
+	os.Exit(code)
 }
 
-// parseFlag parses a flag in the current line. If returns (nil, false) if the
-// line does not match.
-func (h *Help) parseFlag() (f *Flag, found bool) {
-	line := h.scanner.Text()
-	m := regexFlag.FindStringSubmatch(line)
-	found = m != nil
-	if found {
-		f = new(Flag)
-		switch {
-		case m[2] != "": // short flag
-			f.Name = m[2]
-			f.Usage = m[3]
-			return
-		case m[4] != "": // flag with arg
-			f.Name = m[4]
-			f.Arg = m[5]
-		default:
-			f.Name = m[1]
+// setOptions sets the flags of cli from the given include file options, unless
+// they have already been set on the command line.
+func setOptions(cli *flag.FlagSet, options []*h2m.Option) error {
+	set := make(map[string]bool)
+	cli.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, o := range options {
+		f := cli.Lookup(o.Name)
+		if f == nil {
+			return fmt.Errorf("line %d: unknown option -%s", o.Line, o.Name)
+		}
+		switch o.Name {
+		case "config", "help", "include", "opt-include", "version":
+			return fmt.Errorf("line %d: option -%s cannot be used in an include file", o.Line, o.Name)
+		}
+		if set[o.Name] {
+			continue
+		}
+		value := o.Value
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() && value == "" {
+			value = "true"
+		}
+		if err := cli.Set(o.Name, value); err != nil {
+			return fmt.Errorf("line %d: invalid value %q for option -%s: %w", o.Line, value, o.Name, err)
 		}
 	}
-	return
+	return nil
 }
 
-// parseFlags parses flags from the internal reader. It will continue until
-// the current line does not look like a flag or a flag description, leaving
-// the current line to be parsed.
-func (h *Help) parseFlags() {
-	// TODO: maybe group together flags with the same description
-	// TODO: maybe group together short flag without description
-	//       and the only flag that start with this letter?
-	for {
-		if f, found := h.parseFlag(); found {
-			var text strings.Builder
-			if f.Usage != "" {
-				text.WriteString(f.Usage)
-			}
-			h.Flags = append(h.Flags, f)
-			for h.scanner.Scan() {
-				line := h.scanner.Text()
-				if regexFUsage.MatchString(line) {
-					text.WriteString("\n")
-					text.WriteString(strings.TrimSpace(line))
-				} else {
-					break
-				}
-			}
-			f.Usage = strings.TrimSpace(text.String())
-		} else {
-			break
+// exitCodes is a set of accepted exit codes. A nil set accepts all of them.
+type exitCodes map[int]bool
+
+// parseExitCodes parses a comma separated list of exit codes, or "any".
+func parseExitCodes(s string) (exitCodes, error) {
+	if strings.TrimSpace(s) == "any" {
+		return nil, nil
+	}
+	codes := make(exitCodes)
+	for _, f := range strings.Split(s, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || code < 0 {
+			return nil, fmt.Errorf("invalid exit code %q", f)
 		}
+		codes[code] = true
 	}
+	return codes, nil
 }
 
-func (h *Help) parseHeader() (header string, found bool) {
-	line := h.scanner.Text()
-	m := regexHeader.FindStringSubmatch(line)
-	if m != nil {
-		return m[1], true
-	}
-	return "", false
+// accept reports whether the given exit code is part of the set. Negative
+// codes, used when the process has been killed, are never accepted.
+func (c exitCodes) accept(code int) bool {
+	return code >= 0 && (c == nil || c[code])
 }
 
-// parse parses the help message from the internal reader.
-func (h *Help) parse() error {
-	var s *Section = &Section{Title: "DESCRIPTION"}
-	var text strings.Builder
-	finaliseSection := func() {
-		s.Text = strings.TrimSpace(text.String())
-		if s.Text != "" {
-			h.Sections[s.Title] = s
-		}
-		text.Reset()
-	}
-
-	for h.scanner.Scan() {
-		h.parseUsage()
-		h.parseFlags()
-		if hr, found := h.parseHeader(); found {
-			if title, found := findKnownSection(hr); found {
-				finaliseSection()
-				s = &Section{Title: title}
-				continue
-			}
-		}
-		text.Write(h.scanner.Bytes())
-		text.WriteString("\n")
+// programName returns the name of the program from exe, which may be the
+// path of an executable, a directory or an import path.
+func programName(exe string) string {
+	if exe == "" {
+		return ""
 	}
-	finaliseSection()
-	return h.scanner.Err()
-}
-
-// sectionMarkup returns the text of a known section if found, ready to be
-// written on the output man page as is.
-func (h *Help) sectionMarkup(title string) (markup string, found bool) {
-	s, found := h.Sections[title]
-	b := &strings.Builder{}
-	if found {
-		efprintln(b, s.Text)
-	}
-	switch title {
-	case "OPTIONS":
-		found = true
-		for _, f := range h.Flags {
-			if f.Arg != "" {
-				efprintf(b, ".TP\n\\fB\\-%s\\fR %s\n", f.Name, f.Arg)
-			} else {
-				efprintf(b, ".TP\n\\fB\\-%s\\fR\n", f.Name)
-			}
-			efprintln(b, f.Usage)
+	name := filepath.Base(exe)
+	if name == "." || name == ".." {
+		if abs, err := filepath.Abs(exe); err == nil {
+			name = filepath.Base(abs)
 		}
 	}
-	markup = b.String()
-	return
+	return strings.TrimSuffix(name, ".exe")
 }
 
-type Include struct {
-	Sections      map[string]*Section
-	OtherSections []*Section
+// isPackage reports whether exe designates a Go package instead of an
+// executable, i.e. if it is a directory, or an import path that is neither
+// an existing file nor a command found in PATH.
+func isPackage(exe string) bool {
+	if fi, err := os.Stat(exe); err == nil {
+		return fi.IsDir()
+	}
+	if build.IsLocalImport(exe) || filepath.IsAbs(exe) || !strings.Contains(exe, "/") {
+		return false
+	}
+	_, err := exec.LookPath(exe)
+	return err != nil
 }
 
-func readInclude(path string, optional bool) (include *Include, err error) {
-	f, err := os.Open(path)
+// buildPackage builds the main Go package pkg in a new temporary directory,
+// with the given build flags, and returns the path of the executable. The
+// returned clean function removes the temporary directory. The go command
+// of GOROOT is used if set, e.g. by "go generate".
+func buildPackage(pkg string, flags []string) (exe string, clean func(), err error) {
+	dir, err := os.MkdirTemp("", Name+"-")
 	if err != nil {
-		if optional {
-			return &Include{}, nil
-		}
-		return nil, err
+		return "", nil, err
 	}
-	include, err = parseInclude(bufio.NewReader(f))
+	remove := func() { os.RemoveAll(dir) }
+	defer func() {
+		if err != nil {
+			remove()
+		}
+	}()
+	goCmd := "go"
+	if root := os.Getenv("GOROOT"); root != "" {
+		goCmd = filepath.Join(root, "bin", "go")
+	}
+	args := append([]string{"build", "-o", dir + string(filepath.Separator)}, flags...)
+	cmd := exec.Command(goCmd, append(args, pkg)...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", nil, fmt.Errorf("run %s: %w", cmd, err)
+	}
+	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return nil, fmt.Errorf("parse: %w", err)
+		return "", nil, err
 	}
-	return
-}
-
-// parseInclude parses an .h2m include file.
-func parseInclude(r io.Reader) (*Include, error) {
-	i := &Include{Sections: make(map[string]*Section)}
-
-	var s *Section
-	var text strings.Builder
-	finaliseSection := func() {
-		if s != nil {
-			s.Text = strings.TrimSpace(text.String())
-		}
-		text.Reset()
-	}
-
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := scanner.Text()
-		m := regexSection.FindStringSubmatch(line)
-		if m != nil {
-			finaliseSection()
-			s = &Section{}
-			title := m[1]
-			switch r := m[1][0]; r {
-			case '<', '=', '>':
-				s.Pos = r
-				title = m[1][1:]
-			}
-			title, found := findKnownSection(title)
-			s.Title = title
-			if found {
-				i.Sections[title] = s
-			} else {
-				i.OtherSections = append(i.OtherSections, s)
-			}
-			continue
-		}
-		text.WriteString(line)
-		text.WriteString("\n")
+	if len(entries) != 1 {
+		return "", nil, fmt.Errorf("%s is not a main package", pkg)
 	}
-	finaliseSection()
-	return i, scanner.Err()
+	return filepath.Join(dir, entries[0].Name()), remove, nil
 }
 
-// getHelp runs the given exe with the -help flag to return its output.
-func getHelp(exe string) ([]byte, error) {
-	cmd := exec.Command(exe, "-help")
+// getHelp runs the given exe with the given help option to return its output.
+// Non-zero exit codes are accepted as long as they are part of codes.
+func getHelp(exe string, option []string, codes exitCodes) ([]byte, error) {
+	cmd := exec.Command(exe, option...)
 	out, err := cmd.CombinedOutput()
+	exitErr := &exec.ExitError{}
+	if errors.As(err, &exitErr) {
+		if codes.accept(exitErr.ExitCode()) {
+			err = nil
+		} else {
+			return nil, fmt.Errorf("run %s: %w (see -exit-codes)", cmd, err)
+		}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("run %s: %w", cmd, err)
 	}
@@ -359,243 +249,298 @@ func getHelp(exe string) ([]byte, error) {
 	return out, err
 }
 
-// version returns the current version of gohelp2man as found in build info.
-func version() string {
-	v := "(unknown)"
-	info, ok := debug.ReadBuildInfo()
-	if ok {
-		v = info.Main.Version
+// inferVersion returns the version of exe from its build info, or else from
+// the first line of its output when run with the given version option.
+func inferVersion(exe string, option []string, codes exitCodes) (string, error) {
+	if v, err := h2m.ExecutableVersion(exe); err == nil {
+		return v, nil
 	}
-	return v
-}
-
-// now returns the current time or the value of SOURCE_DATE_EPOCH if defined.
-func now() time.Time {
-	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
-		unixEpoch, err := strconv.ParseInt(epoch, 10, 64)
-		if err != nil {
-			panic("invalid SOURCE_DATE_EPOCH: " + err.Error())
+	out, err := getHelp(exe, option, codes)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line, nil
 		}
-		return time.Unix(unixEpoch, 0)
-	} else {
-		return time.Now()
 	}
+	return "", fmt.Errorf("run %s: empty output", exe)
 }
 
-var blockEscaper = NewRegexpReplacer(
-	`-`, `\-`,
-	`\\`, `\(rs`,
-	"\n\n+", "\n.PP\n",
-	`(?m)^\.`, `\&.`,
-	`(?m)^\'`, `\&'`,
-)
-
-var blockFormatter = NewRegexpReplacer(
-	// Format second level headers
-	`(?m)^(?:.PP\n)?(\w.*):\s*$`, `.SS $1:`,
-	// Format man(1) style notation
-	`\b(\w|\w(?:\\-|\w|\.|:)*\w)\((\w+)\)\B`, `\fB$1\fP($2)`,
-	// Format -flag in bold
-	`\B(\\-(?:\\-|\w)*\w)\b`, `\fB$1\fP`,
-)
-
-var fieldEscaper = NewRegexpReplacer(
-	`-`, `\-`,
-	`"`, `\(dq`,
-)
-
-// must panics if err is not nil.
-func must(n int, err error) int {
+// getSubcommandHelp runs the given exe with the name of a subcommand followed
+// by the help option, and returns its help output parsed like the one of parent.
+func getSubcommandHelp(exe, name string, option []string, codes exitCodes, parent *h2m.Help) (*h2m.Help, error) {
+	out, err := getHelp(exe, append([]string{name}, option...), codes)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	help := h2m.NewHelp(bytes.NewBuffer(out))
+	help.Dialect, help.Syntax = parent.Dialect, parent.Syntax
+	if err := help.Parse(); err != nil {
+		return nil, fmt.Errorf("parse output: %w", err)
 	}
-	return n
+	return help, nil
 }
 
-// mfprint is [fmt.Fprint] wrapped with [must].
-func mfprint(w io.Writer, args ...any) int {
-	return must(fmt.Fprint(w, args...))
+// readHelp returns the content of the given help file, or of the standard
+// input if path is "-".
+func readHelp(path string) ([]byte, error) {
+	var out []byte
+	var err error
+	if path == "-" {
+		path = "standard input"
+		out, err = io.ReadAll(os.Stdin)
+	} else {
+		out, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("read %s: empty content", path)
+	}
+	return out, nil
 }
 
-// mfprintln is [fmt.Fprintln] wrapped with [must].
-func mfprintln(w io.Writer, args ...any) int {
-	return must(fmt.Fprintln(w, args...))
-}
+// listFlag is a [flag.Value] that accumulates the values of a repeated flag.
+type listFlag []string
 
-// mfprintf is [fmt.Fprintf] wrapped with [must].
-func mfprintf(w io.Writer, format string, args ...any) int {
-	return must(fmt.Fprintf(w, format, args...))
+func (f *listFlag) String() string {
+	return strings.Join(*f, " ")
 }
 
-// e escapes and formats a value to be included as is in a man page as a block of text.
-func e(v any) string {
-	escaped := blockEscaper.Replace(fmt.Sprint(v))
-	return blockFormatter.Replace(escaped)
+func (f *listFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
 }
 
-func eArgs(args []any) []any {
-	eargs := make([]any, len(args))
-	for i, arg := range args {
-		eargs[i] = e(arg)
+// parseAliases parses lists of comma separated flag names.
+func parseAliases(lists []string) (aliases [][]string) {
+	for _, list := range lists {
+		var names []string
+		for _, n := range strings.Split(list, ",") {
+			names = append(names, strings.TrimLeft(strings.TrimSpace(n), "-"))
+		}
+		aliases = append(aliases, names)
 	}
-	return eargs
-}
-
-// efprint is [mfprint] with all args escaped with [e].
-func efprint(w io.Writer, args ...any) int {
-	return mfprint(w, eArgs(args)...)
-}
-
-// efprintln is [mfprintln] with all args escaped with [e].
-func efprintln(w io.Writer, args ...any) int {
-	return mfprintln(w, eArgs(args)...)
+	return
 }
 
-// efprintf is [mfprintf] with all args escaped with [e].
-func efprintf(w io.Writer, format string, args ...any) int {
-	return mfprintf(w, format, eArgs(args)...)
+// regexDates match the date of the man pages, preceded by their first group,
+// by format.
+var regexDates = map[h2m.Format]*regexp.Regexp{
+	h2m.FormatMan:  regexp.MustCompile(`(?m)^(\.TH \S+ \S+ )\S+`),
+	h2m.FormatHTML: regexp.MustCompile(`(<footer><span>[^<]*</span><span>)[^<]*`),
+	h2m.FormatMdoc: regexp.MustCompile(`(?m)^(\.Dd ).*`),
 }
 
-// writeSynopsis formats a synopsis line by writing the command name in bold
-// and the arguments inside brackets in italic.
-func writeSynopsis(w io.Writer, synopsis string) {
-	name, rest, found := strings.Cut(strings.TrimSpace(synopsis), " ")
-	if !found {
-		efprintf(w, "\\fB%s\\fR", name)
-		return
+// checkManPage compares the given content with the one of the man page at
+// path, which is considered empty if it does not exist. The date of the man
+// page, written according to format, is ignored unless SOURCE_DATE_EPOCH is
+// set. The differences are printed to w and it reports whether the man page
+// is up to date.
+func checkManPage(w io.Writer, path string, format h2m.Format, content []byte) (bool, error) {
+	current, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
 	}
-	splits := strings.Split(rest, "\n"+name+" ")
-	re := regexp.MustCompile(`\[([^[]+)\]`)
-	for i, args := range splits {
-		if i != 0 {
-			mfprint(w, ".br\n")
-		}
-		efprintf(w, "\\fB%s\\fR ", name)
-		mfprintln(w, re.ReplaceAllString(e(args), `[\fI${1}\fR]`))
+	if re := regexDates[format]; re != nil && os.Getenv("SOURCE_DATE_EPOCH") == "" {
+		current = re.ReplaceAll(current, []byte("${1}DATE"))
+		content = re.ReplaceAll(content, []byte("${1}DATE"))
 	}
-}
-
-// writeKnownSection writes the section with given title in w if it is present
-// at least in i or h. It withHeader is true and the section is found, then
-// the title of this section it prependend to the section's text.
-//
-// The text from i is written first, and if the section is present in both i
-// and h, then they will be in different paragraphs.
-func writeKnownSection(w io.Writer, i *Include, h *Help, title string) {
-	si, foundi := i.Sections[title]
-	sh, foundh := h.sectionMarkup(title)
-	if !foundi && !foundh {
-		return
-	}
-	mfprintf(w, ".SH %s\n", title)
-	switch {
-	case foundi && foundh:
-		switch si.Pos {
-		case '>':
-			mfprint(w, sh)
-			mfprintln(w, ".PP")
-			mfprintln(w, si.Text)
-		case '=':
-			mfprintln(w, si.Text)
-		case '<':
-			fallthrough
-		default:
-			mfprintln(w, si.Text)
-			mfprintln(w, ".PP")
-			mfprint(w, sh)
-		}
-	case foundi:
-		mfprintln(w, si.Text)
-	case foundh:
-		mfprint(w, sh)
+	diff := unifiedDiff(path, path+" (generated)", string(current), string(content))
+	if diff == "" {
+		return true, nil
 	}
+	_, err = io.WriteString(w, diff)
+	return false, err
 }
 
-func writeManPage(w io.Writer, name, description, v string, include *Include, help *Help, section, manual string) (err error) {
-	defer func() {
-		if !debugMode {
-			if p := recover(); p != nil {
-				err = fmt.Errorf("%v", p)
-			}
-		}
-	}()
+// writeMode is the way a man page is written by [writeManPage].
+type writeMode int
 
-	// Write generator comment
-	mfprintf(w, ".\\\" Generated by %s %s; DO NOT EDIT.\n", Name, version())
+const (
+	// modeWrite writes the man page at its path.
+	modeWrite writeMode = iota
+	// modeCheck checks the man page at its path with [checkManPage].
+	modeCheck
+	// modePreview formats the man page as text on the standard output
+	// with [h2m.WritePreview].
+	modePreview
+)
 
-	// Write title
-	mfprintf(w, `.TH %s %v %s "%s"`,
-		fieldEscaper.Replace(strings.ToUpper(name)),
-		section,
-		now().Format("2006-01-02"),
-		fieldEscaper.Replace(v),
-	)
-	if manual != "" {
-		mfprintf(w, ` "%s"`, fieldEscaper.Replace(manual))
+// writeManPage writes the man page made by [h2m.WriteFormat] at path, or to
+// the standard output if path is empty. In check mode, the man page at path is
+// checked instead with [checkManPage], and it reports whether it is up to
+// date. In preview mode, the path is ignored.
+func writeManPage(path string, mode writeMode, format h2m.Format, p *h2m.Page, include *h2m.Include, help *h2m.Help) (bool, error) {
+	var b bytes.Buffer
+	if err := h2m.WriteFormat(&b, format, p, include, help); err != nil {
+		return false, err
 	}
-	mfprintln(w)
-
-	// Write NAME section
-	efprintf(w, ".SH NAME\n%v \\- %v\n", name, description)
-
-	// Write SYNOPSIS section
-	mfprintln(w, ".SH SYNOPSIS")
-	if s, found := include.Sections["SYNOPSIS"]; found {
-		mfprintln(w, s.Text)
-	} else if help.Usage != "" {
-		writeSynopsis(w, help.Usage)
-	} else {
-		efprintf(w, "\\fB%s\\fR [\\fIOPTION\\fR]... [\\fIARGUMENT\\fR]...\n", name)
+	if mode == modePreview {
+		return true, h2m.WritePreview(os.Stdout, b.String(), terminalWidth(), isTerminal(os.Stdout))
 	}
+	return writeOutput(path, mode, format, b.Bytes())
+}
 
-	// Write DESCRIPTION section
-	writeKnownSection(w, include, help, "DESCRIPTION")
-
-	// Write OPTIONS section
-	writeKnownSection(w, include, help, "OPTIONS")
-
-	// Write other included sections
-	for _, s := range include.OtherSections {
-		mfprintf(w, ".SH %s\n%s\n", s.Title, s.Text)
+// writeOutput writes content at path, or to the standard output if path is
+// empty. In check mode, the file at path is checked instead with
+// [checkManPage] as a man page in format, and it reports whether it is up to
+// date.
+func writeOutput(path string, mode writeMode, format h2m.Format, content []byte) (bool, error) {
+	switch {
+	case mode == modeCheck:
+		return checkManPage(os.Stdout, path, format, content)
+	case path == "":
+		_, err := os.Stdout.Write(content)
+		return true, err
+	default:
+		return true, os.WriteFile(path, content, 0o666)
 	}
+}
+
+// isTerminal reports whether f is a terminal, and colours are not disabled
+// by NO_COLOR.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0 && os.Getenv("NO_COLOR") == ""
+}
 
-	// Write last known sections
-	for _, title := range KnownSections[4:] {
-		writeKnownSection(w, include, help, title)
+// terminalWidth returns the width of the terminal from COLUMNS, like man(1),
+// or else 80.
+func terminalWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
 	}
-	return
+	return 80
 }
 
 func main() {
+	defer cleanup()
 	cli := flag.NewFlagSet(Name, flag.ExitOnError)
 	cli.Usage = func() {
-		fmt.Fprintf(cli.Output(), Usage, Name, Name)
+		fmt.Fprintf(cli.Output(), Usage, Name, Name, Name, Name, Name)
 		cli.PrintDefaults()
 	}
 	var (
+		flagAliases       listFlag
+		flagBuildFlags    string
+		flagCheck         bool
+		flagCompletion    string
+		flagConfig        string
+		flagDialect       string
+		flagDocSection    string
+		flagExitCodes     string
+		flagExplain       bool
+		flagFormat        string
 		flagHelp          bool
+		flagHelpFile      string
+		flagHelpOption    string
 		flagInclude       string
+		flagInferVersion  bool
 		flagManual        string
+		flagModuleInfo    bool
 		flagName          string
+		flagNoGroup       bool
 		flagOptInclude    string
 		flagOutput        string
+		flagPreview       bool
+		flagProgram       string
 		flagSection       string
+		flagSepDefaults   bool
+		flagStatic        bool
+		flagStrict        bool
+		flagSubcommands   string
+		flagSubPages      bool
+		flagSyntax        string
 		flagVersion       bool
+		flagVersionOption string
 		flagVersionString string
 	)
+	cli.Var(&flagAliases, "alias", "Document the comma separated `NAMES` as aliases of a single option.\n"+
+		"Can be repeated.")
+	cli.StringVar(&flagBuildFlags, "build-flags", "", "Pass the space separated `FLAGS` to \"go build\" when EXECUTABLE is a\n"+
+		"Go package. GOFLAGS is also taken into account.")
+	cli.BoolVar(&flagCheck, "check", false, "Do not write the manual page, but compare it with the -output file\n"+
+		"and print their differences. Exit with a non-zero status if they\n"+
+		"differ. The date is ignored unless SOURCE_DATE_EPOCH is set.")
+	cli.StringVar(&flagCompletion, "completion", "", "Write the completion script of EXECUTABLE for `SHELL`, among \"bash\",\n"+
+		"\"zsh\" or \"fish\", instead of its manual page. The arguments of the\n"+
+		"options named like FILE, PATH or DIR are completed with file names.")
+	cli.StringVar(&flagConfig, "config", "", "Generate in parallel the manual pages listed in the configuration\n"+
+		"`FILE`, instead of the one of EXECUTABLE. The other options given on\n"+
+		"the command line apply to all the pages, except the ones of a single\n"+
+		"page like -output, which must be set in FILE.")
+	cli.StringVar(&flagDialect, "dialect", "flag", "Set the `DIALECT` of the help output, i.e. the library used to print it,\n"+
+		"among \"flag\", \"cobra\" or \"urfave\". It defines the recognised headers and\n"+
+		"the syntax of the options.")
+	cli.StringVar(&flagDocSection, "doc-section", "", "Use the doc comment of the Go package of EXECUTABLE, or else of the\n"+
+		"current directory, as the `SECTION` of the manual page (e.g.\n"+
+		"DESCRIPTION), unless it is given by the include file. Like in include\n"+
+		"files, it can start with <, = or > to place the text before, in place\n"+
+		"of, or after the help output.")
+	cli.StringVar(&flagExitCodes, "exit-codes", "0", "Accept the comma separated `CODES` as exit codes of EXECUTABLE when\n"+
+		"getting its help output. Use \"any\" to accept all of them.")
+	cli.BoolVar(&flagExplain, "explain", false, "Do not write the manual page, but print each line of the help output\n"+
+		"with its number, the way it has been parsed (e.g. \"flag\" or \"header\")\n"+
+		"and the section of the manual page where it ended.")
+	cli.StringVar(&flagFormat, "format", "man", "Write the manual page in `FORMAT`, among \"man\" for roff, \"mdoc\" for\n"+
+		"semantic roff, \"markdown\" for CommonMark, \"html\" for a standalone\n"+
+		"web page or \"json\" for the parsed help output and include file. The\n"+
+		"roff of the include file is translated to the other formats, limited\n"+
+		"to the common requests.")
 	cli.BoolVar(&flagHelp, "help", false, "Show this help and exit.")
+	cli.StringVar(&flagHelpFile, "help-file", "", "Read the help output from `FILE` instead of running EXECUTABLE. If FILE\n"+
+		"is -, read standard input. The program name must then be given by\n"+
+		"EXECUTABLE, the [NAME] section of the include file or -program.")
+	cli.StringVar(&flagHelpOption, "help-option", "-help", "Set the `OPTION` passed to EXECUTABLE to get its help output. It is\n"+
+		"split on spaces, which allows to prepend a subcommand (e.g. \"build -h\").")
 	cli.StringVar(&flagInclude, "include", "", "Include material from `FILE`.")
+	cli.BoolVar(&flagInferVersion, "infer-version", false, "Infer the version of EXECUTABLE when -version-string is not set, from\n"+
+		"its build info if it is a Go program, or else from the first line of\n"+
+		"its output when run with the -version-option.")
 	cli.StringVar(&flagManual, "manual", "", "Set the name of the manual section to `SECTION`, used as a centred\n"+
 		"heading for the manual page. By default it is omitted to let man(1)\n"+
 		"fill it accordingly. Commonly used values are \"User Commands\" for\n"+
 		"pages in section 1, \"Games\" for section 6 and \"System Administration\n"+
 		"Utilities\" for sections 8 and 1M.")
+	cli.BoolVar(&flagModuleInfo, "module-info", false, "Fill the REPORTING BUGS, COPYRIGHT and SEE ALSO sections from the\n"+
+		"metadata of the Go module of EXECUTABLE if it is a Go package, or else\n"+
+		"of the current directory, unless they are given by the include file.")
 	cli.StringVar(&flagName, "name", "", "Description for the NAME paragraph.")
+	cli.BoolVar(&flagNoGroup, "no-group", false, "Do not group the options that share the same argument and description,\n"+
+		"when one of them is named by a single letter.")
 	cli.StringVar(&flagOptInclude, "opt-include", "", "A variant of -include which does not require `FILE` to exist.")
 	cli.StringVar(&flagOutput, "output", "", "Send output to `FILE` rather than stdout.")
+	cli.BoolVar(&flagPreview, "preview", false, "Do not write the manual page, but format it as text on standard\n"+
+		"output to review it without man(1). It is styled if standard output is\n"+
+		"a terminal, unless NO_COLOR is set.")
+	cli.StringVar(&flagProgram, "program", "", "Set the program `NAME` instead of deriving it from EXECUTABLE.")
 	cli.StringVar(&flagSection, "section", "1", "Set the section of the manual page to `NUMBER` (e.g. 1, 6 or 8). See\n"+
 		"man(1) for common section numbers.")
+	cli.BoolVar(&flagSepDefaults, "separate-defaults", false, "Write the default value of each option in italic on its own line,\n"+
+		"instead of at the end of its description.")
+	cli.BoolVar(&flagStatic, "static", false, "Extract the options from the source code of the Go package given as\n"+
+		"EXECUTABLE (an import path or a directory), instead of running it.\n"+
+		"Only the options whose name is a constant are documented.")
+	cli.BoolVar(&flagStrict, "strict", false, "Exit with a non-zero status if warnings were printed, e.g. for options\n"+
+		"without usage, lines that look like unparsed options or duplicate\n"+
+		"sections in the help output or the include file. Unknown sections of\n"+
+		"the include file are only reported if they look like misspelled\n"+
+		"standard sections or request a placement.")
+	cli.StringVar(&flagSubcommands, "subcommands", "", "Document the comma separated `LIST` of subcommands, by running\n"+
+		"EXECUTABLE with the subcommand name prepended to the help option. Use\n"+
+		"\"auto\" to document all the commands found in the help output.")
+	cli.BoolVar(&flagSubPages, "subcommand-pages", false, "Write a separate manual page for each subcommand next to the -output\n"+
+		"file, instead of documenting them in the COMMANDS section.")
+	cli.StringVar(&flagSyntax, "syntax", "", "Set the `SYNTAX` of the options in the help output: \"go\" for the\n"+
+		"\"flag\" package, \"gnu\" for GNU style options as printed by pflag\n"+
+		"(e.g. \"-o, --output string\"), \"urfave\" for urfave/cli or \"auto\"\n"+
+		"to recognise both go and gnu. Defaults to the syntax of the dialect.")
 	cli.BoolVar(&flagVersion, "version", false, "Show version number and exit.")
+	cli.StringVar(&flagVersionOption, "version-option", "-version", "Set the `OPTION` passed to EXECUTABLE to get its version with\n"+
+		"-infer-version. It is split on spaces like -help-option.")
 	cli.StringVar(&flagVersionString, "version-string", "", "Set the `VERSION` to use in the footer.")
 
 	envOpts := strings.Fields(os.Getenv("GOH2M_OPTIONS"))
@@ -607,45 +552,173 @@ func main() {
 	}
 
 	if flagVersion {
-		fmt.Println(Name, version())
+		fmt.Println(Name, h2m.Version())
 		os.Exit(0)
 	}
 
 	exe := cli.Arg(0)
-	if exe == "" {
+	if flagConfig != "" {
+		if exe != "" {
+			l.Fatalln("-config cannot be used with EXECUTABLE")
+		}
+		if names := visitedPageFlags(cli); len(names) != 0 {
+			l.Fatalf("-config cannot be used with %s, set them for each page of the configuration file", strings.Join(names, ", "))
+		}
+		self, err := os.Executable()
+		if err != nil {
+			l.Fatalln("config:", err)
+		}
+		if err := runConfig(self, flagConfig, visitedArgs(cli, "config")); err != nil {
+			l.Fatalln("config:", err)
+		}
+		return
+	}
+	if exe == "" && flagHelpFile == "" {
 		l.Print("missing argument: executable")
 		cli.Usage()
 		os.Exit(2)
 	}
 
-	include := &Include{}
+	include := &h2m.Include{}
 	hasOptInclude, hasInclude := flagOptInclude != "", flagInclude != ""
 	if hasOptInclude && hasInclude {
 		l.Fatalln("-opt-include and -include cannot be specified at the same time")
 	}
 	var err error
+	includePath := flagInclude
 	if hasOptInclude {
-		include, err = readInclude(flagOptInclude, true)
+		includePath = flagOptInclude
+	}
+	if includePath != "" {
+		include, err = h2m.ReadInclude(includePath, hasOptInclude)
 	}
-	if hasInclude {
-		include, err = readInclude(flagInclude, false)
+	if err == nil {
+		err = setOptions(cli, include.Options)
 	}
 	if err != nil {
 		l.Fatalln("include file:", err)
 	}
+	warnAll(includePath, include.Warnings)
 
-	out, err := getHelp(exe)
+	switch flagSyntax {
+	case "", h2m.SyntaxAuto, h2m.SyntaxGo, h2m.SyntaxGNU, h2m.SyntaxUrfave:
+	default:
+		l.Fatalf("-syntax: unknown syntax %q", flagSyntax)
+	}
+	dialect, found := h2m.Dialects[flagDialect]
+	if !found {
+		l.Fatalf("-dialect: unknown dialect %q", flagDialect)
+	}
+	format, err := h2m.ParseFormat(flagFormat)
 	if err != nil {
-		l.Fatalln("get help:", err)
+		l.Fatalln("-format:", err)
 	}
-	help := NewHelp(bytes.NewBuffer(out))
-	err = help.parse()
+	codes, err := parseExitCodes(flagExitCodes)
 	if err != nil {
-		l.Fatalln("parse output:", err)
+		l.Fatalln("-exit-codes:", err)
+	}
+	helpOption := strings.Fields(flagHelpOption)
+	name := programName(exe)
+	pkg := "."
+	buildPkg := !flagStatic && flagHelpFile == "" && isPackage(exe)
+	if flagStatic || buildPkg {
+		pkg = exe
+	}
+	if buildPkg {
+		bin, clean, err := buildPackage(exe, strings.Fields(flagBuildFlags))
+		if err != nil {
+			l.Fatalln("build package:", err)
+		}
+		cleanups = append(cleanups, clean)
+		exe, name = bin, programName(bin)
+	}
+	var help *h2m.Help
+	if flagStatic {
+		if flagHelpFile != "" {
+			l.Fatalln("-static cannot be used with -help-file")
+		}
+		if flagExplain {
+			l.Fatalln("-explain cannot be used with -static")
+		}
+		help, err = h2m.FromPackage(exe)
+		if err != nil {
+			l.Fatalln("static extraction:", err)
+		}
+	} else {
+		var out []byte
+		if flagHelpFile != "" {
+			out, err = readHelp(flagHelpFile)
+		} else {
+			out, err = getHelp(exe, helpOption, codes)
+		}
+		if err != nil {
+			l.Fatalln("get help:", err)
+		}
+		help = h2m.NewHelp(bytes.NewBuffer(out))
+		help.Dialect, help.Syntax = dialect, flagSyntax
+		err = help.Parse()
+		if err != nil {
+			l.Fatalln("parse output:", err)
+		}
+		warnAll("help output", help.Warnings)
 	}
+	if flagExplain {
+		if err := help.Explain(os.Stdout); err != nil {
+			l.Fatalln("explain:", err)
+		}
+		return
+	}
+	help.SeparateDefaults = flagSepDefaults
+	if flagModuleInfo {
+		info, err := h2m.ReadModuleInfo(pkg)
+		if err != nil {
+			l.Fatalln("module info:", err)
+		}
+		for title, s := range info.Sections() {
+			_, foundi := include.Sections[title]
+			_, foundh := help.Sections[title]
+			if !foundi && !foundh {
+				help.Sections[title] = s
+			}
+		}
+	}
+	if flagDocSection != "" {
+		text, err := h2m.PackageDoc(pkg)
+		if err != nil {
+			l.Fatalln("package doc:", err)
+		}
+		include.AddSection(flagDocSection, text)
+	}
+	aliases := parseAliases(flagAliases)
+	help.GroupFlags(aliases, !flagNoGroup)
 
-	name := filepath.Base(exe)
-	description := "manual page for " + name
+	var subcommands []*h2m.Command
+	if flagSubcommands != "" {
+		if flagHelpFile != "" || flagStatic {
+			l.Fatalln("-subcommands cannot be used with -help-file or -static")
+		}
+		if flagSubPages && flagOutput == "" {
+			l.Fatalln("-subcommand-pages requires -output")
+		}
+		subcommands = help.Commands
+		if flagSubcommands != "auto" {
+			subcommands = nil
+			for _, n := range strings.Split(flagSubcommands, ",") {
+				subcommands = append(subcommands, help.Command(strings.TrimSpace(n)))
+			}
+		}
+		for _, c := range subcommands {
+			c.Help, err = getSubcommandHelp(exe, c.Name, helpOption, codes, help)
+			if err != nil {
+				l.Fatalf("subcommand %s: %v", c.Name, err)
+			}
+			warnAll("help output of "+c.Name, c.Help.Warnings)
+			c.Help.SeparateDefaults = flagSepDefaults
+			c.Help.GroupFlags(aliases, !flagNoGroup)
+		}
+	}
+
+	var description string
 	if s, found := include.Sections["NAME"]; found {
 		n, d, ok := strings.Cut(s.Text, " - ")
 		if !ok {
@@ -655,39 +728,110 @@ func main() {
 			l.Fatalf("illegal character %q in program name: %q", n[i], n)
 		}
 		name, description = n, d
+	} else if s, found := help.Sections["NAME"]; found {
+		// Some dialects print a NAME section, used if it is valid.
+		n, d, ok := strings.Cut(s.Text, " - ")
+		if ok && strings.IndexAny(n, " \t\n\r") == -1 {
+			name, description = n, d
+		}
+	}
+	if flagProgram != "" {
+		name = flagProgram
+	}
+	if name == "" {
+		l.Fatalln("missing program name: use -program or a [NAME] section")
 	}
 	if flagName != "" {
 		description = flagName
 	}
-	v := flagVersionString
-	fields := strings.Fields(v)
-	switch len(fields) {
-	case 0:
-		v = name
-	case 1:
-		v = name + " " + fields[0]
-	default:
-		v = strings.Join(fields, " ")
+	if flagInferVersion && flagVersionString == "" {
+		if flagHelpFile != "" || flagStatic {
+			l.Fatalln("-infer-version cannot be used with -help-file or -static")
+		}
+		flagVersionString, err = inferVersion(exe, strings.Fields(flagVersionOption), codes)
+		if err != nil {
+			l.Fatalln("infer version:", err)
+		}
+	}
+	page := &h2m.Page{
+		Name:        name,
+		Description: description,
+		Version:     flagVersionString,
+		Section:     flagSection,
+		Manual:      flagManual,
 	}
 
-	var w io.Writer
-	if flagOutput != "" {
-		file, err := os.Create(flagOutput)
+	if flagCheck && flagOutput == "" {
+		l.Fatalln("-check requires -output")
+	}
+	if flagCompletion != "" {
+		if flagPreview {
+			l.Fatalln("-completion and -preview cannot be used at the same time")
+		}
+		var b bytes.Buffer
+		if err := h2m.WriteCompletion(&b, flagCompletion, name, help); err != nil {
+			l.Fatalln("-completion:", err)
+		}
+		mode := modeWrite
+		if flagCheck {
+			mode = modeCheck
+		}
+		// Completion scripts have no date.
+		ok, err := writeOutput(flagOutput, mode, "", b.Bytes())
 		if err != nil {
-			l.Fatalln("create output file:", err)
+			l.Fatalln("write completion:", err)
+		}
+		if !ok {
+			l.Fatalln("completion script is not up to date")
+		}
+		if flagStrict && warnings != 0 {
+			l.Fatalf("-strict: %d warnings", warnings)
+		}
+		return
+	}
+	mode := modeWrite
+	switch {
+	case flagCheck && flagPreview:
+		l.Fatalln("-check and -preview cannot be used at the same time")
+	case flagCheck:
+		mode = modeCheck
+	case flagPreview:
+		if format != h2m.FormatMan {
+			l.Fatalln("-preview requires the man format")
+		}
+		mode = modePreview
+	}
+	upToDate := true
+	if flagSubPages {
+		for _, c := range subcommands {
+			subpage := *page
+			subpage.Name = name + "-" + c.Name
+			subpage.Description = c.Usage
+			c.Help.References = append(c.Help.References, name+"("+flagSection+")")
+			path := filepath.Join(filepath.Dir(flagOutput), subpage.Name+"."+format.Extension(flagSection))
+			ok, err := writeManPage(path, mode, format, &subpage, nil, c.Help)
+			if err != nil {
+				l.Fatalf("write man page %s: %v", path, err)
+			}
+			upToDate = upToDate && ok
+			help.References = append(help.References, subpage.Name+"("+flagSection+")")
+			c.Help = nil
 		}
-		w = bufio.NewWriter(file)
-	} else {
-		w = os.Stdout
 	}
-	b := bufio.NewWriter(w)
 
 	// Print man page
-	err = writeManPage(b, name, description, v, include, help, flagSection, flagManual)
+	ok, err := writeManPage(flagOutput, mode, format, page, include, help)
 	if err != nil {
 		l.Fatalln("write man page:", err)
 	}
-	if err := b.Flush(); err != nil {
-		l.Fatalln("print man page:", err)
+	upToDate = upToDate && ok
+	for _, p := range include.UnmatchedPatterns() {
+		warnf("include pattern /%s/ did not match any paragraph", p.Regexp)
+	}
+	if flagStrict && warnings != 0 {
+		l.Fatalf("-strict: %d warnings", warnings)
+	}
+	if !upToDate {
+		l.Fatalln("man pages are not up to date")
 	}
 }
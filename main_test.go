@@ -20,403 +20,275 @@ package main
 import (
 	"bytes"
 	"errors"
+	"flag"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"syscall"
 	"testing"
 	"unsafe"
+
+	"github.com/n-peugnet/gohelp2man/h2m"
 )
 
-func TestParseUsage(t *testing.T) {
+func TestSetOptions(t *testing.T) {
 	cases := []struct {
-		name  string
-		val   string
-		usage string
+		name    string
+		args    []string
+		options []*h2m.Option
+		section string
+		verbose bool
+		err     string
 	}{
-		{
-			"empty string",
-			"",
-			"",
-		},
-		{
-			"short flag",
-			"  -h	Show help and exit.",
-			"",
-		},
-		{
-			"go flag default",
-			"Usage of gohelp2man:",
-			"",
-		},
-		{
-			"custom GNU-like",
-			"Usage: gohelp2man [OPTION]... EXECUTABLE",
-			"gohelp2man [OPTION]... EXECUTABLE",
-		},
-		{
-			"multiline GNU-like",
-			`Usage: ln [OPTION]... [-T] TARGET LINK_NAME
-  or:  ln [OPTION]... TARGET
-  or:  ln [OPTION]... TARGET... DIRECTORY
-  or:  ln [OPTION]... -t DIRECTORY TARGET...
-In the 1st form, create a link to TARGET with the name LINK_NAME.`,
-			`ln [OPTION]... [-T] TARGET LINK_NAME
-ln [OPTION]... TARGET
-ln [OPTION]... TARGET... DIRECTORY
-ln [OPTION]... -t DIRECTORY TARGET...`,
-		},
-		{
-			"multiline go-like",
-			`Usage of stringer:
-	stringer [flags] -type T [directory]
-	stringer [flags] -type T files... # Must be a single package
-For more information, see:
-	https://pkg.go.dev/golang.org/x/tools/cmd/stringer`,
-			`stringer [flags] -type T [directory]
-stringer [flags] -type T files... # Must be a single package`,
-		},
+		{"empty", nil, nil, "1", false, ""},
+		{"set", nil, []*h2m.Option{{Name: "section", Value: "8", Line: 1}}, "8", false, ""},
+		{"command line precedence", []string{"-section", "6"}, []*h2m.Option{{Name: "section", Value: "8", Line: 1}}, "6", false, ""},
+		{"bool without value", nil, []*h2m.Option{{Name: "verbose", Value: "", Line: 1}}, "1", true, ""},
+		{"unknown", nil, []*h2m.Option{{Name: "unknown", Value: "", Line: 2}}, "1", false, "line 2: unknown option -unknown"},
+		{"forbidden", nil, []*h2m.Option{{Name: "include", Value: "file.h2m", Line: 1}}, "1", false, "cannot be used"},
+		{"forbidden config", nil, []*h2m.Option{{Name: "config", Value: "pages.json", Line: 2}}, "1", false, "line 2: option -config cannot be used"},
+		{"invalid", nil, []*h2m.Option{{Name: "verbose", Value: "maybe", Line: 3}}, "1", false, "line 3: invalid value"},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			help := NewHelp(strings.NewReader(c.val))
-			help.scanner.Scan()
-			help.parseUsage()
-			if !reflect.DeepEqual(c.usage, help.Usage) {
-				t.Fatalf("expected:\n%v\ngot:\n%v", c.usage, help.Usage)
+			cli := flag.NewFlagSet("test", flag.ContinueOnError)
+			section := cli.String("section", "1", "")
+			verbose := cli.Bool("verbose", false, "")
+			cli.String("include", "", "")
+			cli.String("config", "", "")
+			if err := cli.Parse(c.args); err != nil {
+				t.Fatal(err)
+			}
+			err := setOptions(cli, c.options)
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("expected error to contain %q, got %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *section != c.section || *verbose != c.verbose {
+				t.Fatalf("expected %q %v, got %q %v", c.section, c.verbose, *section, *verbose)
 			}
 		})
 	}
 }
 
-func TestParseFlags(t *testing.T) {
+func TestParseExitCodes(t *testing.T) {
 	cases := []struct {
-		name  string
-		val   string
-		flag  *Flag
-		found bool
+		name     string
+		input    string
+		expected exitCodes
+		err      bool
 	}{
-		{
-			"empty string",
-			"",
-			nil,
-			false,
-		},
-		{
-			"synopsis",
-			"Usage of compose-spec:",
-			nil,
-			false,
-		},
-		{
-			"simple short",
-			"  -h	Show help and exit.",
-			&Flag{"h", "", "Show help and exit."},
-			true,
-		},
-		{
-			"multi short",
-			`  -h	Show help
-    	and exit.`,
-			&Flag{"h", "", "Show help\nand exit."},
-			true,
-		},
-		{
-			"simple long",
-			`  -help
-    	Show help and exit.`,
-			&Flag{"help", "", "Show help and exit."},
-			true,
-		},
-		{
-			"multi long",
-			`  -help
-    	Show help
-    	and exit.`,
-			&Flag{"help", "", "Show help\nand exit."},
-			true,
-		},
-		{
-			"simple arg",
-			`  -fmt string
-    	Output format (yaml|json). (default "yaml")`,
-			&Flag{"fmt", "string", `Output format (yaml|json). (default "yaml")`},
-			true,
-		},
-		{
-			"kebab case",
-			`  -kebab-case
-    	Flag using kebab case.`,
-			&Flag{"kebab-case", "", "Flag using kebab case."},
-			true,
-		},
-		{
-			"single digit",
-			"  -6	Use IPv6 protocol.",
-			&Flag{"6", "", "Use IPv6 protocol."},
-			true,
-		},
-		{
-			"short with custom arg",
-			`  -t V
-    	Use V as test. (default "test")`,
-			&Flag{"t", "V", `Use V as test. (default "test")`},
-			true,
-		},
-		{
-			"custom arg with space",
-			`  -test V V
-    	Use V V as test. (default "test")
-`,
-			&Flag{"test", "V V", `Use V V as test. (default "test")`},
-			true,
-		},
+		{"single", "0", exitCodes{0: true}, false},
+		{"multiple", "0, 2", exitCodes{0: true, 2: true}, false},
+		{"any", "any", nil, false},
+		{"empty", "", nil, true},
+		{"negative", "-1", nil, true},
+		{"invalid", "0,two", nil, true},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			help := NewHelp(strings.NewReader(c.val))
-			help.scanner.Scan()
-			help.parseFlags()
-			if c.found {
-				if len(help.Flags) == 0 {
-					t.Fatal("expected to get one flag, got none")
-				}
-				f := help.Flags[0]
-				if !reflect.DeepEqual(c.flag, f) {
-					t.Fatalf("expected:\n%v\ngot:\n%v", c.flag, f)
-				}
-			} else {
-				if len(help.Flags) != 0 {
-					t.Fatal("expected to get no flags, got ", help.Flags)
-				}
+			actual, err := parseExitCodes(c.input)
+			if (err != nil) != c.err {
+				t.Fatalf("expected error: %v, got %v", c.err, err)
+			}
+			if !reflect.DeepEqual(c.expected, actual) {
+				t.Fatalf("expected %v, got %v", c.expected, actual)
 			}
 		})
 	}
 }
 
-func TestParse(t *testing.T) {
+func TestGetHelp(t *testing.T) {
 	cases := []struct {
-		name string
-		val  string
-		help *Help
-		err  string
+		name   string
+		script string
+		codes  exitCodes
+		out    string
+		err    string
 	}{
-		{
-			name: "empty string",
-			val:  "",
-			help: &Help{},
-		},
-		{
-			name: "description before usage",
-			val: `A test help message.
-
-Usage: test [OPTION]... ARG
-`,
-			help: &Help{
-				Usage: "test [OPTION]... ARG",
-				Sections: map[string]*Section{
-					"DESCRIPTION": {"DESCRIPTION", "A test help message.", 0},
-				},
-			},
-		},
-		{
-			name: "description after usage",
-			val: `Usage: test [OPTION]... ARG
-
-A test help message.
-`,
-			help: &Help{
-				Usage: "test [OPTION]... ARG",
-				Sections: map[string]*Section{
-					"DESCRIPTION": {"DESCRIPTION", "A test help message.", 0},
-				},
-			},
-		},
-		{
-			name: "description after flags",
-			val: `Usage: test [OPTION]... ARG
-  -h	Show help.
-
-A test help message.
-`,
-			help: &Help{
-				Usage: "test [OPTION]... ARG",
-				Flags: []*Flag{{"h", "", "Show help."}},
-				Sections: map[string]*Section{
-					"DESCRIPTION": {"DESCRIPTION", "A test help message.", 0},
-				},
-			},
-		},
-		{
-			name: "options header",
-			val: `Text of the description.
-
-Options:
-  -h	Show help.
-`,
-			help: &Help{
-				Flags: []*Flag{{"h", "", "Show help."}},
-				Sections: map[string]*Section{
-					"DESCRIPTION": {"DESCRIPTION", "Text of the description.", 0},
-				},
-			},
-		},
-		{
-			name: "unknown section header",
-			val: `Other section:
-Text of this section.
-`,
-			help: &Help{Sections: map[string]*Section{
-				"DESCRIPTION": {"DESCRIPTION", "Other section:\nText of this section.", 0},
-			}},
-		},
-		{
-			name: "known header after flags",
-			val: `Text of the description.
-  -h	Show help.
-Author:
-Nicolas Peugnet
-`,
-			help: &Help{
-				Flags: []*Flag{{"h", "", "Show help."}},
-				Sections: map[string]*Section{
-					"DESCRIPTION": {"DESCRIPTION", "Text of the description.", 0},
-					"AUTHOR":      {"AUTHOR", "Nicolas Peugnet", 0},
-				},
-			},
-		},
+		{"success", "echo usage", exitCodes{0: true}, "usage\n", ""},
+		{"stderr", "echo usage >&2", exitCodes{0: true}, "usage\n", ""},
+		{"accepted exit code", "echo usage; exit 2", exitCodes{0: true, 2: true}, "usage\n", ""},
+		{"any exit code", "echo usage; exit 3", nil, "usage\n", ""},
+		{"rejected exit code", "echo usage; exit 2", exitCodes{0: true}, "", "exit status 2 (see -exit-codes)"},
+		{"empty output", "exit 2", nil, "", "empty output"},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			help := NewHelp(strings.NewReader(c.val))
-			err := help.parse()
+			out, err := getHelp("sh", []string{"-c", c.script}, c.codes)
 			if c.err != "" {
-				if !strings.Contains(err.Error(), c.err) {
-					t.Fatalf("expected error to contain %q, got %q", c.err, err)
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("expected error to contain %q, got %v", c.err, err)
 				}
 				return
 			}
-			if help.Usage != c.help.Usage {
-				t.Errorf("expected usage:\n%v\ngot:\n%v", c.help.Usage, help.Usage)
-			}
-			if !reflect.DeepEqual(c.help.Flags, help.Flags) {
-				t.Errorf("expected flags:\n%v\ngot:\n%v", c.help.Flags, help.Flags)
-			}
-			if c.help.Sections == nil {
-				c.help.Sections = make(map[string]*Section)
+			if err != nil {
+				t.Fatal(err)
 			}
-			if !reflect.DeepEqual(c.help.Sections, help.Sections) {
-				t.Errorf("expected sections:\n%v\ngot:\n%v", c.help.Sections, help.Sections)
+			if string(out) != c.out {
+				t.Fatalf("expected %q, got %q", c.out, out)
 			}
 		})
 	}
 }
 
-func TestParseInclude(t *testing.T) {
+func TestIsPackage(t *testing.T) {
 	cases := []struct {
-		name     string
-		input    string
-		expected *Include
+		exe      string
+		expected bool
 	}{
-		{
-			"empty",
-			"",
-			&Include{Sections: map[string]*Section{}},
-		},
-		{
-			"single known section",
-			`[NAME]
-gohelp2man - generate a simple manual page for Go programs
-`,
-			&Include{Sections: map[string]*Section{
-				"NAME": {
-					Title: "NAME",
-					Text:  "gohelp2man - generate a simple manual page for Go programs",
-				},
-			}},
-		},
-		{
-			"lowercase known section",
-			`[name]
-gohelp2man - generate a simple manual page for Go programs
-`,
-			&Include{Sections: map[string]*Section{
-				"NAME": {
-					Title: "NAME",
-					Text:  "gohelp2man - generate a simple manual page for Go programs",
-				},
-			}},
-		},
-		{
-			"single other section",
-			`[Other section]
-This is a section that is not known.
-`,
-			&Include{Sections: map[string]*Section{}, OtherSections: []*Section{
-				{
-					Title: "OTHER SECTION",
-					Text:  "This is a section that is not known.",
-				},
-			}},
-		},
-		{
-			"positioned known section",
-			"[>DESCRIPTION]\nAppend\n",
-			&Include{Sections: map[string]*Section{
-				"DESCRIPTION": {"DESCRIPTION", "Append", '>'},
-			}},
-		},
+		{"testdata/test.sh", false},
+		{"testdata/hello", true},
+		{".", true},
+		{"sh", false},
+		{"example.com/cmd/hello", true},
+		{"./nonexistent/hello", false},
+		{"/nonexistent/hello", false},
 	}
 	for _, c := range cases {
-		t.Run(c.name, func(t *testing.T) {
-			actual, err := parseInclude(strings.NewReader(c.input))
-			if err != nil {
-				t.Error(err)
-			}
-			if !reflect.DeepEqual(c.expected, actual) {
+		t.Run(c.exe, func(t *testing.T) {
+			if actual := isPackage(c.exe); actual != c.expected {
 				t.Fatalf("expected %v, got %v", c.expected, actual)
 			}
 		})
 	}
 }
 
-func TestWriteSynopsis(t *testing.T) {
+func TestBuildPackage(t *testing.T) {
+	exe, clean, err := buildPackage("./testdata/hello", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := programName(exe); name != "hello" {
+		t.Errorf("expected program name %q, got %q", "hello", name)
+	}
+	out, err := getHelp(exe, []string{"-help"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "The NAME to greet.") {
+		t.Errorf("unexpected help output:\n%s", out)
+	}
+	clean()
+	if _, err := os.Stat(filepath.Dir(exe)); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed, got %v", filepath.Dir(exe), err)
+	}
+
+	_, _, err = buildPackage("./testdata", nil)
+	if err == nil {
+		t.Fatal("expected an error when building a non-Go directory")
+	}
+}
+
+func TestReadHelp(t *testing.T) {
+	expected, err := os.ReadFile("testdata/test_full_basic.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := readHelp("testdata/test_full_basic.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+
+	stdin, err := os.Open("testdata/test_full_basic.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	prevStdin := os.Stdin
+	t.Cleanup(func() { os.Stdin = prevStdin })
+	os.Stdin = stdin
+	actual, err = readHelp("-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+
+	_, err = readHelp(os.DevNull)
+	if err == nil || !strings.Contains(err.Error(), "empty content") {
+		t.Fatalf("expected empty content error, got %v", err)
+	}
+}
+
+func TestCheckManPage(t *testing.T) {
+	dir := t.TempDir()
+	man := ".TH TEST 1 2025-01-01 \"test\"\n.SH NAME\ntest\n"
+	mdoc := ".Dd January 1, 2025\n.Dt TEST 1\n.Os\n"
+	html := "<main></main>\n<footer><span>test</span><span>2025-01-01</span><span>TEST(1)</span></footer>\n"
 	cases := []struct {
 		name     string
-		input    string
-		expected string
+		format   h2m.Format
+		page     string
+		epoch    string
+		content  string
+		upToDate bool
+		diff     string
 	}{
-		{"basic", "test [OPTION]... [ARGUMENT]...", `\fBtest\fR [\fIOPTION\fR]... [\fIARGUMENT\fR]...`},
-		{"non-closed brackets", "test [argument", `\fBtest\fR [argument`},
-		{"end with lbracket", "test argument[", `\fBtest\fR argument[`},
-		{"end with rbracket", "test argument]", `\fBtest\fR argument]`},
-		{"single bracketed arg", "test [argument]", `\fBtest\fR [\fIargument\fR]`},
-		{"no args", "test", `\fBtest\fR`},
-		{"no args with space", "test ", `\fBtest\fR`},
-		{"empty", "", `\fB\fR`},
-		{"single space", "", `\fB\fR`},
-		{"starts with space", " test args", `\fBtest\fR args`},
-		{
-			"basic multiline",
-			`stringer [flags] -type T [directory]
-stringer [flags] -type T files...`,
-			`\fBstringer\fR [\fIflags\fR] \fB\-type\fP T [\fIdirectory\fR]
-.br
-\fBstringer\fR [\fIflags\fR] \fB\-type\fP T files...`,
-		},
+		{"same", h2m.FormatMan, man, "", man, true, ""},
+		{"date ignored", h2m.FormatMan, man, "", strings.Replace(man, "2025-01-01", "2025-02-02", 1), true, ""},
+		{"date checked", h2m.FormatMan, man, "0", strings.Replace(man, "2025-01-01", "2025-02-02", 1), false, "+.TH TEST 1 2025-02-02"},
+		{"stale", h2m.FormatMan, man, "", man + ".SH OPTIONS\n", false, "@@ -1,3 +1,4 @@\n .TH TEST 1 DATE \"test\"\n .SH NAME\n test\n+.SH OPTIONS\n"},
+		{"html date ignored", h2m.FormatHTML, html, "", strings.Replace(html, "2025-01-01", "2025-02-02", 1), true, ""},
+		{"html date checked", h2m.FormatHTML, html, "0", strings.Replace(html, "2025-01-01", "2025-02-02", 1), false, "<span>2025-02-02</span>"},
+		{"mdoc date ignored", h2m.FormatMdoc, mdoc, "", strings.Replace(mdoc, "January 1, 2025", "February 2, 2025", 1), true, ""},
+		{"mdoc date checked", h2m.FormatMdoc, mdoc, "0", strings.Replace(mdoc, "January 1, 2025", "February 2, 2025", 1), false, "+.Dd February 2, 2025"},
+		{"no date", h2m.FormatJSON, man, "", strings.Replace(man, "2025-01-01", "2025-02-02", 1), false, "+.TH TEST 1 2025-02-02"},
 	}
-	for _, c := range cases {
+	for i, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			w := &strings.Builder{}
-			writeSynopsis(w, c.input)
-			actual := strings.TrimSuffix(w.String(), "\n")
-			if actual != c.expected {
-				t.Fatalf("expected:\n%s\ngot:\n%s", c.expected, actual)
+			path := filepath.Join(dir, strconv.Itoa(i))
+			if err := os.WriteFile(path, []byte(c.page), 0o666); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("SOURCE_DATE_EPOCH", c.epoch)
+			var diff strings.Builder
+			upToDate, err := checkManPage(&diff, path, c.format, []byte(c.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if upToDate != c.upToDate {
+				t.Errorf("expected up to date: %v, got %v", c.upToDate, upToDate)
+			}
+			if !strings.Contains(diff.String(), c.diff) || (c.diff == "") != (diff.Len() == 0) {
+				t.Errorf("expected diff to contain:\n%s\ngot:\n%s", c.diff, diff.String())
 			}
 		})
 	}
+
+	var diff strings.Builder
+	upToDate, err := checkManPage(&diff, filepath.Join(dir, "missing"), h2m.FormatMan, []byte(man))
+	if err != nil || upToDate {
+		t.Fatalf("expected missing page to be stale, got %v %v", upToDate, err)
+	}
+}
+
+// TestMdocLint checks the mdoc output with mandoc(1), if it is installed. The
+// style messages are ignored, as some of them depend on the installed pages.
+func TestMdocLint(t *testing.T) {
+	if _, err := exec.LookPath("mandoc"); err != nil {
+		// The CI installs mandoc, so that the test is never skipped there.
+		if os.Getenv("CI") != "" {
+			t.Fatal("mandoc not installed")
+		}
+		t.Skip("mandoc not installed")
+	}
+	cmd := exec.Command("mandoc", "-Tlint", "-W", "warning", filepath.Join("testdata", "test_full_mdoc.mdoc"))
+	if out, err := cmd.CombinedOutput(); err != nil || len(out) != 0 {
+		t.Fatalf("%s: %v\n%s", cmd, err, out)
+	}
 }
 
 func setup(t *testing.T, args ...string) string {
@@ -430,17 +302,79 @@ func setup(t *testing.T, args ...string) string {
 	return out
 }
 
+func TestSubcommandPages(t *testing.T) {
+	out := setup(t, "-subcommands", "build,serve", "-subcommand-pages")
+	t.Setenv("GOHELP2MAN_TESTCASE", "testdata/test_full_subcommands.txt")
+	t.Setenv("SOURCE_DATE_EPOCH", "0")
+	main()
+	expected := map[string][]string{
+		"out":             {".SH COMMANDS\n.TP\n\\fBbuild\\fR\n", ".SH SEE ALSO\n.BR test.sh\\-build (1),\n.BR test.sh\\-serve (1)\n"},
+		"test.sh-build.1": {".SH NAME\ntest.sh\\-build \\- Build the project.\n", "\\fB\\-o\\fR FILE\n", ".SH SEE ALSO\n.BR test.sh (1)\n"},
+		"test.sh-serve.1": {".SH NAME\ntest.sh\\-serve \\- Serve the project.\n", "\\fB\\-addr\\fR ADDR\n"},
+	}
+	for file, parts := range expected {
+		actual, err := os.ReadFile(filepath.Join(filepath.Dir(out), file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, part := range parts {
+			if !strings.Contains(string(actual), part) {
+				t.Errorf("expected %s to contain:\n%s\ngot:\n%s", file, part, actual)
+			}
+		}
+		if file == "out" && strings.Contains(string(actual), ".SS build") {
+			t.Errorf("expected %s not to document build inline:\n%s", file, actual)
+		}
+	}
+}
+
 func isatty() bool {
 	var size uint64
 	_, _, err := syscall.Syscall(syscall.SYS_IOCTL, os.Stderr.Fd(), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&size)))
 	return err == 0
 }
 
+// goldenExtensions are the extensions of the expected outputs of TestFull by
+// format, roff being the default.
+var goldenExtensions = map[string]string{
+	"html":     ".html",
+	"json":     ".json",
+	"markdown": ".md",
+	"mdoc":     ".mdoc",
+}
+
+// goldenExtension returns the extension of the expected output of a TestFull
+// case with the given arguments.
+func goldenExtension(args []string) string {
+	for _, a := range args {
+		if strings.HasPrefix(a, "-format=") {
+			if ext, found := goldenExtensions[strings.TrimPrefix(a, "-format=")]; found {
+				return ext
+			}
+		}
+	}
+	return ".1"
+}
+
 func TestFull(t *testing.T) {
 	cases := []string{
 		"basic",
+		"cobra",
+		"defaults",
 		"escapes",
 		"formatting",
+		"gnu",
+		"grouped",
+		"help_file",
+		"html",
+		"include_options",
+		"json",
+		"markdown",
+		"mdoc",
+		"patterns",
+		"subcommands",
+		"urfave",
+		"version",
 		"with_headers",
 	}
 	for _, c := range cases {
@@ -455,7 +389,8 @@ func TestFull(t *testing.T) {
 			t.Setenv("GOHELP2MAN_TESTCASE", basename+".txt")
 			t.Setenv("SOURCE_DATE_EPOCH", "0")
 			main()
-			expected, err := os.ReadFile(basename + ".1")
+			golden := basename + goldenExtension(args)
+			expected, err := os.ReadFile(golden)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -468,7 +403,7 @@ func TestFull(t *testing.T) {
 				if isatty() {
 					args = []string{"--color=always"}
 				}
-				args = append(args, "-u", "--label=expected", "--label=got", basename+".1", out)
+				args = append(args, "-u", "--label=expected", "--label=got", golden, out)
 				cmd := exec.Command("diff", args...)
 				diff, err := cmd.Output()
 				exitErr := &exec.ExitError{}
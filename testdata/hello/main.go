@@ -0,0 +1,12 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+func main() {
+	name := flag.String("name", "world", "The `NAME` to greet.")
+	flag.Parse()
+	fmt.Printf("Hello %s!\n", *name)
+}
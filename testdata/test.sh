@@ -1,5 +1,10 @@
 #!/bin/sh
+testcase=$GOHELP2MAN_TESTCASE
+if [ "$1" != "-help" ]
+then
+	testcase=${testcase%.txt}_$1.help
+fi
 while IFS= read -r line
 do
 	printf "%s\n" "$line"
-done < $GOHELP2MAN_TESTCASE
+done < $testcase
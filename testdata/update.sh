@@ -2,6 +2,13 @@
 export SOURCE_DATE_EPOCH=0
 for f in testdata/test_full_*.txt
 do
+	case "$(grep -x -- '-format=.*' "${f%.txt}.args" 2> /dev/null)" in
+	-format=html) ext=html ;;
+	-format=json) ext=json ;;
+	-format=markdown) ext=md ;;
+	-format=mdoc) ext=mdoc ;;
+	*) ext=1 ;;
+	esac
 	cat "${f%.txt}.args" 2> /dev/null \
-	| xargs -d'\n' sh -x -c "GOHELP2MAN_TESTCASE=$f go run . -opt-include ${f%.txt}.h2m \"\$@\" testdata/test.sh" "go" > "${f%.txt}.1"
+	| xargs -d'\n' sh -x -c "GOHELP2MAN_TESTCASE=$f go run . -opt-include ${f%.txt}.h2m \"\$@\" testdata/test.sh" "go" > "${f%.txt}.$ext"
 done